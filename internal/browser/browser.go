@@ -0,0 +1,26 @@
+package browser
+
+import (
+	"os/exec"
+	"runtime"
+)
+
+// Open launches the user's default browser on url without waiting for it.
+func Open(url string) error {
+	var cmd string
+	var args []string
+
+	switch runtime.GOOS {
+	case "windows":
+		// cmd /c start would let the shell run whatever follows a & in a
+		// URL picked by the sender
+		cmd = "rundll32"
+		args = []string{"url.dll,FileProtocolHandler"}
+	case "darwin":
+		cmd = "open"
+	default:
+		cmd = "xdg-open"
+	}
+	args = append(args, url)
+	return exec.Command(cmd, args...).Start()
+}
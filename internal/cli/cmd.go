@@ -1,16 +1,26 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
 	"github.com/sverdejot/geemail/internal/cli/tui"
 	"github.com/sverdejot/geemail/internal/gmail"
 	"github.com/sverdejot/geemail/internal/gmail/auth"
+	"github.com/sverdejot/geemail/internal/inbox"
+	"github.com/sverdejot/geemail/internal/journal"
+	"github.com/sverdejot/geemail/internal/store"
 )
 
+// about six months, enough to tell apart a newsletter read now and then
+const defaultEngagementDays = 180
+
 var rootCmd = &cobra.Command{
 	Use:   "geemail",
 	Short: "Fast, bulk Gmail inbox cleanup",
@@ -21,29 +31,237 @@ var rootCmd = &cobra.Command{
 			return fmt.Errorf("error reading flag: %w", err)
 		}
 
-		ctx := cmd.Context()
-		client, err := auth.NewHTTPClient(ctx)
+		g, err := groupingFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		sortFlag, err := cmd.Flags().GetString("sort")
+		if err != nil {
+			return fmt.Errorf("error reading flag: %w", err)
+		}
+		sortBy, err := inbox.ParseSortOrder(sortFlag)
+		if err != nil {
+			return fmt.Errorf("invalid --sort: %w", err)
+		}
+
+		concurrency, err := cmd.Flags().GetInt("concurrency")
+		if err != nil {
+			return fmt.Errorf("error reading flag: %w", err)
+		}
+
+		confirmThreshold, err := cmd.Flags().GetInt("confirm-threshold")
 		if err != nil {
-			return fmt.Errorf("unable to create default HTTP client: %v", err)
+			return fmt.Errorf("error reading flag: %w", err)
 		}
 
-		service, err := gmail.NewMessageService(ctx, client)
+		skipReversibleConfirm, err := cmd.Flags().GetBool("no-confirm-reversible")
 		if err != nil {
-			return fmt.Errorf("unable to create message service: %v", err)
+			return fmt.Errorf("error reading flag: %w", err)
 		}
-		m, err := tui.NewRoot(ctx, service, dryRun)
+
+		engagementDays, err := cmd.Flags().GetInt("engagement-days")
+		if err != nil {
+			return fmt.Errorf("error reading flag: %w", err)
+		}
+		if sortBy == inbox.SortByEngagement && engagementDays == 0 {
+			engagementDays = defaultEngagementDays
+		}
+
+		ctx := cmd.Context()
+		service, err := newMailService(cmd)
 		if err != nil {
 			return err
 		}
+		j, err := openJournal(cmd)
+		if err != nil {
+			return err
+		}
+		stack, err := openUndo(cmd)
+		if err != nil {
+			return err
+		}
+
+		subject, err := cmd.Flags().GetString("subject")
+		if err != nil {
+			return fmt.Errorf("error reading flag: %w", err)
+		}
+		var reauth func(context.Context) error
+		if subject == "" {
+			// service accounts have no sign-in to go through again
+			reauth = func(ctx context.Context) error {
+				client, err := auth.Reauthenticate(ctx)
+				if err != nil {
+					return err
+				}
+				return service.UseClient(ctx, client)
+			}
+		}
+
+		m := tui.NewRoot(ctx, service, tui.Config{
+			DryRun:   dryRun,
+			Journal:  j,
+			Undo:     stack,
+			GroupBy:  g.key,
+			Category: g.category,
+			SortBy:   sortBy,
+			View:     g.view,
+
+			EngagementWindow: time.Duration(engagementDays) * 24 * time.Hour,
+			OlderThan:        g.olderThan,
+			NewerThan:        g.newerThan,
+			Concurrency:      concurrency,
+
+			ConfirmThreshold:      confirmThreshold,
+			SkipReversibleConfirm: skipReversibleConfirm,
+			Reauthenticate:        reauth,
+		})
 		if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
 			return fmt.Errorf("error running program: %w", err)
 		}
-		return nil
+		// reported once the alt screen is gone, so it stays on the terminal
+		return m.Err()
 	},
 }
 
+// openJournal loads the unsubscribe journal of the mailbox being cleaned up.
+func openJournal(cmd *cobra.Command) (*journal.Journal, error) {
+	subject, err := cmd.Flags().GetString("subject")
+	if err != nil {
+		return nil, fmt.Errorf("error reading flag: %w", err)
+	}
+	return openState(cmd, func() (*journal.Journal, error) {
+		return journal.Open(subject)
+	}, "unsubscribe journal", "starting with no unsubscribes recorded")
+}
+
+// openState loads state kept across runs, only warning when it had to start
+// over as a broken file should not keep geemail from running.
+func openState[T any](cmd *cobra.Command, open func() (T, error), desc, startOver string) (T, error) {
+	v, err := open()
+	if errors.Is(err, store.ErrCorrupt) {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s, %s\n", err, startOver) //nolint:errcheck
+		return v, nil
+	}
+	if err != nil {
+		return v, fmt.Errorf("unable to open %s: %w", desc, err)
+	}
+	return v, nil
+}
+
+func newMailService(cmd *cobra.Command) (*gmail.MailService, error) {
+	subject, err := cmd.Flags().GetString("subject")
+	if err != nil {
+		return nil, fmt.Errorf("error reading flag: %w", err)
+	}
+
+	ctx := cmd.Context()
+	client, err := newHTTPClient(ctx, subject)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create default HTTP client: %v", err)
+	}
+
+	service, err := gmail.NewMessageService(ctx, client, gmail.WithUser(subject))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create message service: %v", err)
+	}
+	return service, nil
+}
+
+// newHTTPClient impersonates subject through a service account when one is
+// given, falling back to the interactive OAuth flow otherwise.
+func newHTTPClient(ctx context.Context, subject string) (*http.Client, error) {
+	if subject != "" {
+		return auth.NewServiceAccountClient(ctx, subject)
+	}
+	return auth.NewHTTPClient(ctx)
+}
+
+// grouping holds the flags shaping lists, shared by the TUI and the report.
+type grouping struct {
+	key      inbox.GroupKey
+	category inbox.Category
+	view     inbox.ViewMode
+	// only mails received within this window, any age when zero
+	olderThan, newerThan time.Duration
+}
+
+func groupingFlags(cmd *cobra.Command) (grouping, error) {
+	var g grouping
+
+	groupByFlag, err := cmd.Flags().GetString("group-by")
+	if err != nil {
+		return g, fmt.Errorf("error reading flag: %w", err)
+	}
+	g.key, err = inbox.ParseGroupKey(groupByFlag)
+	if err != nil {
+		return g, fmt.Errorf("invalid --group-by: %w", err)
+	}
+
+	categoryFlag, err := cmd.Flags().GetString("category")
+	if err != nil {
+		return g, fmt.Errorf("error reading flag: %w", err)
+	}
+	g.category, err = inbox.ParseCategory(categoryFlag)
+	if err != nil {
+		return g, fmt.Errorf("invalid --category: %w", err)
+	}
+
+	viewFlag, err := cmd.Flags().GetString("view")
+	if err != nil {
+		return g, fmt.Errorf("error reading flag: %w", err)
+	}
+	g.view, err = inbox.ParseViewMode(viewFlag)
+	if err != nil {
+		return g, fmt.Errorf("invalid --view: %w", err)
+	}
+
+	olderThanFlag, err := cmd.Flags().GetString("older-than")
+	if err != nil {
+		return g, fmt.Errorf("error reading flag: %w", err)
+	}
+	g.olderThan, err = inbox.ParseAge(olderThanFlag)
+	if err != nil {
+		return g, fmt.Errorf("invalid --older-than: %w", err)
+	}
+
+	newerThanFlag, err := cmd.Flags().GetString("newer-than")
+	if err != nil {
+		return g, fmt.Errorf("error reading flag: %w", err)
+	}
+	g.newerThan, err = inbox.ParseAge(newerThanFlag)
+	if err != nil {
+		return g, fmt.Errorf("invalid --newer-than: %w", err)
+	}
+	return g, nil
+}
+
 func Execute() {
 	rootCmd.Flags().Bool("dry-run", false, "Simulate all the actions without performing any change")
+	rootCmd.Flags().String("sort", inbox.SortByUnreads.String(), "How to rank lists: unreads, size or engagement")
+	rootCmd.Flags().Int("concurrency", 2, "How many operations may run at once")
+	rootCmd.Flags().Int("confirm-threshold", 50, "Permanently deleting more mails than this asks to type the count or \"delete\"")
+	rootCmd.Flags().Bool("no-confirm-reversible", false, "Archive, trash and report spam without asking first")
+	rootCmd.Flags().Int("engagement-days", 0, "Measure how often each sender was read over this many days, disabled when 0")
+	rootCmd.PersistentFlags().String("group-by", inbox.GroupBySender.String(), "How to group mails: sender, list-id, domain, category or author")
+	rootCmd.PersistentFlags().String("category", "", "Only show mails from a Gmail tab: primary, social, promotions, updates, forums or uncategorized")
+	rootCmd.PersistentFlags().String("view", inbox.ViewMailingLists.String(), "Which senders to show: lists, one-click or all")
+	rootCmd.PersistentFlags().String("older-than", "", "Only consider mails received before this long ago, e.g. 14d, 6mo or 1y, m meaning months")
+	rootCmd.PersistentFlags().String("newer-than", "", "Only consider mails received within this long, e.g. 14d, 6mo or 1y, m meaning months")
+	rootCmd.PersistentFlags().String("subject", "", "Workspace user to impersonate using a service account with domain-wide delegation")
+
+	reportCmd.Flags().String("sort", inbox.SortBySize.String(), "How to rank lists: unreads, size or engagement")
+	reportCmd.Flags().Int("engagement-days", 0, "Measure how often each sender was read over this many days, disabled when 0 unless sorting by engagement")
+	reportCmd.Flags().Int("top", 20, "How many lists to show, 0 for all of them")
+	rootCmd.AddCommand(reportCmd)
+
+	undoCmd.Flags().Int("count", 1, "How many actions to undo, newest first")
+	rootCmd.AddCommand(undoCmd)
+
+	trashRestoreCmd.Flags().String("from", "", "Restore every trashed mail from this sender")
+	trashRestoreCmd.Flags().Bool("all", false, "Restore every mail geemail trashed")
+	trashCmd.AddCommand(trashListCmd, trashRestoreCmd)
+	rootCmd.AddCommand(trashCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sverdejot/geemail/internal/gmail"
+	"github.com/sverdejot/geemail/internal/inbox"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Rank mailing lists by the storage they use",
+	Long:  "Scan unread mails and print the mailing lists taking up the most room, without starting the TUI",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		g, err := groupingFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		sortFlag, err := cmd.Flags().GetString("sort")
+		if err != nil {
+			return fmt.Errorf("error reading flag: %w", err)
+		}
+		sortBy, err := inbox.ParseSortOrder(sortFlag)
+		if err != nil {
+			return fmt.Errorf("invalid --sort: %w", err)
+		}
+
+		top, err := cmd.Flags().GetInt("top")
+		if err != nil {
+			return fmt.Errorf("error reading flag: %w", err)
+		}
+
+		engagementDays, err := cmd.Flags().GetInt("engagement-days")
+		if err != nil {
+			return fmt.Errorf("error reading flag: %w", err)
+		}
+		if sortBy == inbox.SortByEngagement && engagementDays == 0 {
+			engagementDays = defaultEngagementDays
+		}
+
+		service, err := newMailService(cmd)
+		if err != nil {
+			return err
+		}
+
+		mails, err := service.GetUnreadMessages(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("unable to fetch unread messages: %w", err)
+		}
+
+		if w := inbox.CountParseWarnings(mails); w.Total() > 0 {
+			fmt.Fprintf(cmd.ErrOrStderr(), "some headers were malformed and had to be guessed: %s\n", w) //nolint:errcheck
+		}
+
+		opts := []inbox.GroupOpt{
+			inbox.WithGroupKey(g.key),
+			inbox.WithCategory(g.category),
+			inbox.WithViewMode(g.view),
+			inbox.WithSortOrder(sortBy),
+			inbox.WithOlderThan(g.olderThan),
+			inbox.WithNewerThan(g.newerThan),
+		}
+		lists := inbox.GetMailingList(mails, opts...)
+
+		// engagement is only measured for the senders of the lists found,
+		// which are then grouped again to rank them by it
+		if engagementDays > 0 {
+			var senders []string
+			for _, l := range lists {
+				senders = append(senders, l.Senders...)
+			}
+			scores, err := service.SenderEngagement(cmd.Context(), senders, time.Duration(engagementDays)*24*time.Hour)
+			var partial *gmail.EngagementError
+			if err != nil && !errors.As(err, &partial) {
+				return fmt.Errorf("unable to measure engagement: %w", err)
+			}
+			if partial != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: engagement of %d senders could not be measured: %s\n", partial.Failed, partial.Err) //nolint:errcheck
+			}
+			lists = inbox.GetMailingList(mails, append(opts, inbox.WithEngagement(scores))...)
+		}
+		if top > 0 && len(lists) > top {
+			lists = lists[:top]
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', tabwriter.AlignRight)
+		if engagementDays > 0 {
+			fmt.Fprintf(w, "#\t%s\tunread\tsize\tattachments\tage\topened\t\n", g.key) //nolint:errcheck
+		} else {
+			fmt.Fprintf(w, "#\t%s\tunread\tsize\tattachments\tage\t\n", g.key) //nolint:errcheck
+		}
+		for i, l := range lists {
+			fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%d\t%s\t", i+1, cell(l.Key), l.TotalUnreads, inbox.FormatBytes(l.TotalBytes), l.WithAttachments, l.Ages) //nolint:errcheck
+			if engagementDays > 0 {
+				fmt.Fprintf(w, "%s\t", openRate(l)) //nolint:errcheck
+			}
+			fmt.Fprintln(w) //nolint:errcheck
+		}
+		return w.Flush()
+	},
+}
+
+// openRate renders how many of the mails measured were opened, "-" when none
+// was.
+func openRate(l inbox.MailingList) string {
+	if l.Engagement == nil || l.Engagement.Total() == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", l.Engagement.OpenRate()*100)
+}
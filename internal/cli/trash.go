@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/sverdejot/geemail/internal/inbox"
+)
+
+// subjects are cut to this many runes so rows fit a terminal
+const trashSubjectWidth = 50
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "Review and restore mails geemail moved to the trash",
+	Long:  "Find the mails geemail trashed, in this run or earlier ones, while Gmail still keeps them in the trash",
+}
+
+var trashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the mails geemail trashed",
+	Long:  "List the mails geemail trashed that are still in the trash, which Gmail empties after 30 days",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, err := newMailService(cmd)
+		if err != nil {
+			return err
+		}
+
+		mails, err := service.GetTrashedMessages(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("unable to fetch trashed messages: %w", err)
+		}
+		if len(mails) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "no mails trashed by geemail left in the trash") //nolint:errcheck
+			return nil
+		}
+		slices.SortFunc(mails, func(a, b inbox.RawMail) int {
+			return b.Received.Compare(a.Received)
+		})
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "id\tfrom\treceived\tsubject") //nolint:errcheck
+		for _, m := range mails {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", m.ID, cell(m.From), m.Received.Format("2 Jan 2006"), truncate(cell(m.Subject), trashSubjectWidth)) //nolint:errcheck
+		}
+		return w.Flush()
+	},
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:   "restore [id...]",
+	Short: "Move mails geemail trashed back to the inbox",
+	Long:  "Move mails geemail trashed back to the inbox, picked by ID, by sender with --from, or all of them with --all",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, err := cmd.Flags().GetString("from")
+		if err != nil {
+			return fmt.Errorf("error reading flag: %w", err)
+		}
+		all, err := cmd.Flags().GetBool("all")
+		if err != nil {
+			return fmt.Errorf("error reading flag: %w", err)
+		}
+		if len(args) == 0 && from == "" && !all {
+			return errors.New("pick the mails to restore by ID, with --from or with --all")
+		}
+
+		service, err := newMailService(cmd)
+		if err != nil {
+			return err
+		}
+
+		// mails picked by ID only need no lookup
+		ids := args
+		if from != "" || all {
+			mails, err := service.GetTrashedMessages(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("unable to fetch trashed messages: %w", err)
+			}
+			for _, m := range mails {
+				if (all || strings.EqualFold(m.From, from)) && !slices.Contains(args, m.ID) {
+					ids = append(ids, m.ID)
+				}
+			}
+		}
+		if len(ids) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "no trashed mails match") //nolint:errcheck
+			return nil
+		}
+
+		if err := service.RestoreTrashed(cmd.Context(), ids); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "restored %d mails to the inbox\n", len(ids)) //nolint:errcheck
+		return nil
+	},
+}
+
+// cell makes text sent by senders safe to print in a table, dropping escape
+// sequences and the tabs and line breaks that would break its layout.
+func cell(s string) string {
+	return strings.Join(strings.Fields(inbox.Sanitize(s)), " ")
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
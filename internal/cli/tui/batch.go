@@ -0,0 +1,120 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sverdejot/geemail/internal/inbox"
+)
+
+type batchResult struct {
+	mail inbox.MailingList
+	// what was done, or why nothing was
+	detail string
+	err    error
+	// whether the list was left alone on purpose
+	skipped bool
+}
+
+// batch queues an action over several lists at once, and gathers the outcome
+// of each of them as their operations settle.
+type batch struct {
+	op      opKind
+	total   int
+	results []batchResult
+}
+
+func (b *batch) progress() string {
+	return fmt.Sprintf("%s %d/%d", b.op, len(b.results), b.total)
+}
+
+func (b *batch) done() bool {
+	return len(b.results) == b.total
+}
+
+// queueBatch queues op on every list, skipping those it cannot apply to.
+func (m *rootModel) queueBatch(op opKind, mails []inbox.MailingList) tea.Cmd {
+	b := &batch{op: op, total: len(mails)}
+	var cmds []tea.Cmd
+	for _, mail := range mails {
+		target, ok := m.actionTarget(mail)
+		if op == opUnsubscribe {
+			switch mail.UnsubscribeMethod() {
+			case inbox.MethodNone:
+				detail := "no supported unsubscribe method"
+				if len(mail.Senders) > 1 {
+					detail = fmt.Sprintf("gathers %d senders, unsubscribe from them one at a time", len(mail.Senders))
+				}
+				cmds = append(cmds, m.recordBatchResult(b, batchResult{mail: mail, detail: detail, skipped: true}))
+				continue
+			case inbox.MethodLink:
+				// following a link may need the user on the page, which a
+				// batch cannot ask for
+				cmds = append(cmds, m.recordBatchResult(b, batchResult{mail: mail, detail: "only offers a link, unsubscribe from it on its own", skipped: true}))
+				continue
+			}
+		} else if !ok {
+			cmds = append(cmds, m.recordBatchResult(b, batchResult{mail: mail, detail: fmt.Sprintf("no mails older than %s", inbox.FormatAge(m.actionAge)), skipped: true}))
+			continue
+		}
+		cmds = append(cmds, m.enqueue(tracked{kind: op, mail: target, batch: b}))
+	}
+	return tea.Batch(cmds...)
+}
+
+// recordBatchResult reports the progress of b, and its summary once every
+// list is done.
+func (m *rootModel) recordBatchResult(b *batch, r batchResult) tea.Cmd {
+	b.results = append(b.results, r)
+	if b.done() {
+		m.summary = &batchSummary{b: b}
+		return nil
+	}
+	return m.statusCmd("Running batch " + b.progress())
+}
+
+// at most this many results are listed, the rest are only counted
+const summaryMaxLines = 15
+
+// batchSummary lists the outcome of every list in a finished batch.
+type batchSummary struct {
+	b *batch
+}
+
+func (s *batchSummary) Update(msg tea.KeyMsg) tea.Cmd {
+	if key.Matches(msg, confirmCancel) || key.Matches(msg, confirmVisit) {
+		return func() tea.Msg {
+			return batchSummaryClosedMsg{}
+		}
+	}
+	return nil
+}
+
+func (s *batchSummary) View() string {
+	var done, failed, skipped int
+	lines := make([]string, 0, len(s.b.results)+2)
+	for _, r := range s.b.results {
+		switch {
+		case r.err != nil:
+			failed++
+			lines = append(lines, warningStyle.Render(inbox.Sanitize(fmt.Sprintf("✗ %s: %s", r.mail.Key, r.err))))
+		case r.skipped:
+			skipped++
+			lines = append(lines, dialogHelpStyle.Render(inbox.Sanitize(fmt.Sprintf("- %s: %s", r.mail.Key, r.detail))))
+		default:
+			done++
+			lines = append(lines, inbox.Sanitize(fmt.Sprintf("✓ %s: %s", r.mail.Key, r.detail)))
+		}
+	}
+
+	if len(lines) > summaryMaxLines {
+		lines = append(lines[:summaryMaxLines], dialogHelpStyle.Render(fmt.Sprintf("…and %d more", len(lines)-summaryMaxLines)))
+	}
+
+	header := fmt.Sprintf("Batch %s: %d done, %d failed, %d skipped\n", s.b.op, done, failed, skipped)
+	footer := "\n" + dialogHelpStyle.Render("enter/esc close")
+	return dialogStyle.Render(lipgloss.JoinVertical(lipgloss.Left, header, strings.Join(lines, "\n"), footer))
+}
@@ -0,0 +1,209 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sverdejot/geemail/internal/inbox"
+)
+
+// linkConfirm asks the user how to follow an unsubscribe link, since unlike
+// one-click and mailto it may need some interaction on the sender's page.
+type linkConfirm struct {
+	mail inbox.MailingList
+}
+
+func newLinkConfirm(mail inbox.MailingList) *linkConfirm {
+	return &linkConfirm{
+		mail: mail,
+	}
+}
+
+func (c *linkConfirm) Update(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, confirmVisit):
+		return c.confirm(false)
+	case key.Matches(msg, confirmOpen):
+		return c.confirm(true)
+	case key.Matches(msg, confirmCancel):
+		return cancelConfirm
+	}
+	return nil
+}
+
+func (c *linkConfirm) confirm(browser bool) tea.Cmd {
+	return func() tea.Msg {
+		return linkUnsubscribeConfirmedMsg{
+			mail:    c.mail,
+			browser: browser,
+		}
+	}
+}
+
+func (c *linkConfirm) View() string {
+	lines := []string{
+		inbox.Sanitize(fmt.Sprintf("%s only offers an unsubscribe link:", c.mail.From)),
+		c.mail.Unsubscriber.Target() + "\n",
+	}
+	if method, reason := c.mail.UnsubscribeBlocked(); reason != "" {
+		lines = append(lines, warningStyle.Render(fmt.Sprintf("%s unsubscribe was refused: %s", method, reason))+"\n")
+	}
+	lines = append(lines, dialogHelpStyle.Render(fmt.Sprintf(
+		"%s • %s • %s",
+		helpText(confirmVisit),
+		helpText(confirmOpen),
+		helpText(confirmCancel),
+	)))
+
+	return dialogStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+func helpText(b key.Binding) string {
+	return b.Help().Key + " " + b.Help().Desc
+}
+
+// the word to type, besides the count, before a large permanent delete
+const typedConfirmWord = "delete"
+
+// at most this many lists are named in a batch confirmation
+const confirmMaxLists = 3
+
+var confirmVerbs = map[opKind]string{
+	opUnsubscribe: "Unsubscribe and delete",
+	opDelete:      "Delete",
+	opArchive:     "Archive",
+	opTrash:       "Trash",
+	opSpam:        "Report as spam",
+}
+
+// actionConfirm asks before an action touching mails is queued. Permanent
+// deletes of many mails need the count or "delete" typed in, so a stray key
+// press cannot wipe them out.
+type actionConfirm struct {
+	op    opKind
+	mails []inbox.MailingList
+	batch bool
+	// mails touched across every list, and their size
+	total int
+	bytes int64
+	typed bool
+	input textinput.Model
+	// set when what was typed did not match
+	mismatch bool
+}
+
+func newActionConfirm(op opKind, mails []inbox.MailingList, batch bool, total int, bytes int64, threshold int) *actionConfirm {
+	c := &actionConfirm{
+		op:    op,
+		mails: mails,
+		batch: batch,
+		total: total,
+		bytes: bytes,
+		// a filter leaves the mails already there alone
+		typed: !op.reversible() && op != opFilter && total > threshold,
+	}
+	if c.typed {
+		c.input = textinput.New()
+		c.input.Placeholder = strconv.Itoa(total)
+		c.input.CharLimit = 20
+		c.input.Focus()
+	}
+	return c
+}
+
+func (c *actionConfirm) Update(msg tea.KeyMsg) tea.Cmd {
+	if !c.typed {
+		switch {
+		case key.Matches(msg, confirmAction):
+			return c.confirm()
+		case key.Matches(msg, confirmCancel):
+			return cancelConfirm
+		}
+		return nil
+	}
+
+	// letters are part of the answer, only esc cancels
+	switch msg.Type {
+	case tea.KeyEsc:
+		return cancelConfirm
+	case tea.KeyEnter:
+		answer := strings.TrimSpace(c.input.Value())
+		if answer == strconv.Itoa(c.total) || strings.EqualFold(answer, typedConfirmWord) {
+			return c.confirm()
+		}
+		c.mismatch = true
+		c.input.SetValue("")
+		return nil
+	}
+	c.mismatch = false
+	var cmd tea.Cmd
+	c.input, cmd = c.input.Update(msg)
+	return cmd
+}
+
+func (c *actionConfirm) confirm() tea.Cmd {
+	return func() tea.Msg {
+		return actionConfirmedMsg{op: c.op, mails: c.mails, batch: c.batch}
+	}
+}
+
+func cancelConfirm() tea.Msg {
+	return confirmCancelledMsg{}
+}
+
+func (c *actionConfirm) View() string {
+	if c.op == opFilter {
+		return dialogStyle.Render(lipgloss.JoinVertical(lipgloss.Left,
+			fmt.Sprintf("Send every future mail from %s to the trash?\n", c.senders()),
+			"The filter can be removed from Gmail settings afterwards.\n",
+			dialogHelpStyle.Render(fmt.Sprintf("%s • %s", helpText(confirmAction), helpText(confirmCancel))),
+		))
+	}
+
+	question := fmt.Sprintf("%s (%d) mails from %s?", confirmVerbs[c.op], c.total, c.senders())
+	switch c.op {
+	case opDelete, opUnsubscribe:
+		question += fmt.Sprintf(" This frees ~%s.", inbox.FormatBytes(c.bytes))
+	case opTrash:
+		question += fmt.Sprintf(" This frees ~%s once the trash is emptied.", inbox.FormatBytes(c.bytes))
+	}
+	lines := []string{question + "\n"}
+	if c.op.reversible() {
+		lines = append(lines, "They can be brought back from Gmail afterwards.\n")
+	} else {
+		lines = append(lines, warningStyle.Render("This is permanent, the mails cannot be recovered.")+"\n")
+	}
+
+	if !c.typed {
+		lines = append(lines, dialogHelpStyle.Render(fmt.Sprintf("%s • %s", helpText(confirmAction), helpText(confirmCancel))))
+		return dialogStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+	}
+
+	lines = append(lines, fmt.Sprintf("Type %d or %q to confirm:", c.total, typedConfirmWord), c.input.View())
+	if c.mismatch {
+		lines = append(lines, warningStyle.Render("That does not match, try again"))
+	}
+	lines = append(lines, "\n"+dialogHelpStyle.Render("enter confirm • esc cancel"))
+	return dialogStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+// senders names the lists acted upon, only the first few of a batch.
+func (c *actionConfirm) senders() string {
+	if !c.batch {
+		return inbox.Sanitize(c.mails[0].Key)
+	}
+	keys := make([]string, 0, confirmMaxLists)
+	for _, mail := range c.mails[:min(len(c.mails), confirmMaxLists)] {
+		keys = append(keys, mail.Key)
+	}
+	text := fmt.Sprintf("%d lists: %s", len(c.mails), strings.Join(keys, ", "))
+	if rest := len(c.mails) - len(keys); rest > 0 {
+		text += fmt.Sprintf(" and %d more", rest)
+	}
+	return inbox.Sanitize(text)
+}
@@ -0,0 +1,205 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sverdejot/geemail/internal/inbox"
+)
+
+// share of the width taken by the messages, the preview gets the rest
+const detailListRatio = 0.45
+
+// how long the cursor has to stay on a mail before its body is fetched, so
+// scrolling through the list does not queue a fetch for every mail passed
+const previewDelay = 300 * time.Millisecond
+
+// messageItem is a single mail of the list shown in the detail view.
+type messageItem struct {
+	mail inbox.RawMail
+}
+
+func (i messageItem) FilterValue() string {
+	return i.mail.Subject
+}
+
+func (i messageItem) Title() string {
+	subject := inbox.Sanitize(i.mail.Subject)
+	if subject == "" {
+		subject = "(no subject)"
+	}
+	return fmt.Sprintf("%s  %s", i.mail.Received.Format("2 Jan 2006"), subject)
+}
+
+func (i messageItem) Description() string {
+	return inbox.Sanitize(i.mail.Snippet)
+}
+
+// detailModel lists the mails of a list, previewing the selected one.
+type detailModel struct {
+	parent  inbox.MailingList
+	list    list.Model
+	preview viewport.Model
+	// fetches the text of a message, run off the update loop
+	fetch func(id string) (string, error)
+	// bodies fetched so far, by message ID
+	bodies    map[string]string
+	previewID string
+}
+
+func newDetailModel(parent inbox.MailingList, mails []inbox.RawMail, fetch func(string) (string, error)) *detailModel {
+	ids := make(map[string]bool, len(parent.Messages))
+	for _, ref := range parent.Messages {
+		ids[ref.ID] = true
+	}
+	items := make([]list.Item, 0, len(parent.Messages))
+	for _, rm := range mails {
+		if ids[rm.ID] {
+			items = append(items, messageItem{mail: rm})
+		}
+	}
+	slices.SortFunc(items, func(a, b list.Item) int {
+		return b.(messageItem).mail.Received.Compare(a.(messageItem).mail.Received)
+	})
+
+	keys := []key.Binding{deleteMessage, archiveMessage, trashMessage, scrollPreviewDown, scrollPreviewUp, drillUp}
+	l := list.New(items, list.NewDefaultDelegate(), 0, 0)
+	l.Title = inbox.Sanitize(fmt.Sprintf("Mails from %s", parent.Key))
+	l.Styles.Title = titleStyle
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return keys
+	}
+	l.AdditionalFullHelpKeys = func() []key.Binding {
+		return keys
+	}
+
+	return &detailModel{
+		parent:  parent,
+		list:    l,
+		preview: viewport.New(0, 0),
+		fetch:   fetch,
+		bodies:  make(map[string]string),
+	}
+}
+
+func (d *detailModel) SetSize(width, height int) {
+	h, v := appStyle.GetFrameSize()
+	width, height = width-h, height-v
+	listWidth := int(float64(width) * detailListRatio)
+	d.list.SetSize(listWidth, height)
+	d.preview.Width = width - listWidth - previewStyle.GetHorizontalFrameSize()
+	d.preview.Height = height - previewStyle.GetVerticalFrameSize()
+}
+
+// Update handles the keys of the view, and fetches the body of the selected
+// mail once the cursor settles on one not seen yet.
+func (d *detailModel) Update(msg tea.Msg) tea.Cmd {
+	var cmds []tea.Cmd
+
+	switch msg := msg.(type) {
+	case previewDueMsg:
+		// the cursor moved on since
+		if msg.id != d.previewID {
+			return nil
+		}
+		id := msg.id
+		return func() tea.Msg {
+			body, err := d.fetch(id)
+			return bodyFetchedMsg{id: id, body: body, err: err}
+		}
+
+	case bodyFetchedMsg:
+		body := inbox.Sanitize(msg.body)
+		if msg.err != nil {
+			body = warningStyle.Render(fmt.Sprintf("Cannot load this mail: %s", inbox.Sanitize(msg.err.Error())))
+		} else {
+			d.bodies[msg.id] = body
+		}
+		if msg.id == d.previewID {
+			d.preview.SetContent(body)
+		}
+		return nil
+
+	case tea.KeyMsg:
+		if d.list.FilterState() == list.Filtering {
+			break
+		}
+		switch {
+		case key.Matches(msg, scrollPreviewDown):
+			d.preview.HalfPageDown()
+			return nil
+		case key.Matches(msg, scrollPreviewUp):
+			d.preview.HalfPageUp()
+			return nil
+		case key.Matches(msg, deleteMessage):
+			return d.request(func(mail inbox.MailingList) tea.Msg { return deleteRequestMsg{mail: mail} })
+		case key.Matches(msg, archiveMessage):
+			return d.request(func(mail inbox.MailingList) tea.Msg { return archiveRequestMsg{mail: mail} })
+		case key.Matches(msg, trashMessage):
+			return d.request(func(mail inbox.MailingList) tea.Msg { return trashRequestMsg{mail: mail} })
+		}
+	}
+
+	var cmd tea.Cmd
+	d.list, cmd = d.list.Update(msg)
+	cmds = append(cmds, cmd, d.loadPreview())
+	return tea.Batch(cmds...)
+}
+
+// request narrows the list down to the selected mail and asks for an action
+// on it, like the list view does for whole lists.
+func (d *detailModel) request(msg func(inbox.MailingList) tea.Msg) tea.Cmd {
+	item, ok := d.list.SelectedItem().(messageItem)
+	if !ok {
+		return nil
+	}
+	mail, ok := d.parent.Only([]string{item.mail.ID})
+	if !ok {
+		return nil
+	}
+	return func() tea.Msg {
+		return msg(mail)
+	}
+}
+
+func (d *detailModel) loadPreview() tea.Cmd {
+	item, ok := d.list.SelectedItem().(messageItem)
+	if !ok || item.mail.ID == d.previewID {
+		return nil
+	}
+	d.previewID = item.mail.ID
+	d.preview.GotoTop()
+	if body, ok := d.bodies[item.mail.ID]; ok {
+		d.preview.SetContent(body)
+		return nil
+	}
+
+	d.preview.SetContent(dialogHelpStyle.Render("Loading…"))
+	id := item.mail.ID
+	return tea.Tick(previewDelay, func(time.Time) tea.Msg {
+		return previewDueMsg{id: id}
+	})
+}
+
+// remove drops mails handled elsewhere, such as by a per-message action.
+func (d *detailModel) remove(ids []string) {
+	for i := len(d.list.Items()) - 1; i >= 0; i-- {
+		if item, ok := d.list.Items()[i].(messageItem); ok && slices.Contains(ids, item.mail.ID) {
+			d.list.RemoveItem(i)
+		}
+	}
+	d.parent, _ = d.parent.Without(ids)
+}
+
+func (d *detailModel) View() string {
+	return appStyle.Render(lipgloss.JoinHorizontal(lipgloss.Top,
+		d.list.View(),
+		previewStyle.Render(d.preview.View()),
+	))
+}
@@ -0,0 +1,117 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+)
+
+// errorHint tells the likely cause of a failure and what to do about it, or
+// nothing when the error is not recognised.
+func errorHint(err error) string {
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		return "Your sign-in expired or was revoked, sign in again."
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.Code == http.StatusUnauthorized:
+			return "Gmail refused the credentials, sign in again."
+		case apiErr.Code == http.StatusTooManyRequests, isRateLimited(apiErr):
+			return "The Gmail API quota is exhausted, wait a minute and retry."
+		case apiErr.Code >= http.StatusInternalServerError:
+			return "Gmail is having trouble, retry in a while."
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return "Gmail could not be reached, check your network and retry."
+	}
+	return ""
+}
+
+func isRateLimited(err *googleapi.Error) bool {
+	if err.Code != http.StatusForbidden {
+		return false
+	}
+	for _, item := range err.Errors {
+		if strings.Contains(item.Reason, "RateLimitExceeded") || strings.Contains(item.Reason, "rateLimitExceeded") {
+			return true
+		}
+	}
+	return false
+}
+
+// failureView explains why loading stopped and how to go on.
+func (m *rootModel) failureView() string {
+	if m.reauthenticating {
+		return dialogStyle.Render(lipgloss.JoinVertical(lipgloss.Left,
+			"Waiting for you to sign in from the browser…\n",
+			dialogHelpStyle.Render(helpText(quitApp)),
+		))
+	}
+
+	lines := []string{
+		warningStyle.Render("Cannot load your inbox"),
+		"",
+		lipgloss.NewStyle().Width(min(max(m.width-10, 20), 80)).Render(m.err.Error()),
+	}
+	if hint := errorHint(m.err); hint != "" {
+		lines = append(lines, "", hint)
+	}
+	if m.reauthErr != nil {
+		lines = append(lines, "",
+			warningStyle.Render("Cannot sign in again"),
+			lipgloss.NewStyle().Width(min(max(m.width-10, 20), 80)).Render(m.reauthErr.Error()),
+		)
+	}
+
+	help := []string{helpText(retryLoading)}
+	if m.reauth != nil {
+		help = append(help, helpText(reauthenticate))
+	}
+	help = append(help, helpText(quitApp))
+	lines = append(lines, "", dialogHelpStyle.Render(strings.Join(help, " • ")))
+
+	return dialogStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+// updateFailed handles the keys of the error screen.
+func (m *rootModel) updateFailed(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, quitApp):
+		return tea.Quit
+	case m.reauthenticating:
+		return nil
+	case key.Matches(msg, retryLoading):
+		return m.reload()
+	case key.Matches(msg, reauthenticate) && m.reauth != nil:
+		m.reauthenticating = true
+		return func() tea.Msg {
+			return reauthenticatedMsg{err: m.reauth(m.ctx)}
+		}
+	}
+	return nil
+}
+
+// reload scans the inbox again from scratch.
+func (m *rootModel) reload() tea.Cmd {
+	m.state = loading
+	m.err = nil
+	m.reauthErr = nil
+	m.mails = m.mails[:0]
+	m.progress = NewProgressModel(0)
+	m.progress.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+	return tea.Batch(m.progress.Init(), m.startLoading())
+}
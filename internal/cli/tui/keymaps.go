@@ -23,6 +23,152 @@ var (
 		key.WithHelp("t", "trash all mails from this sender"),
 	)
 
+	toggleMark = key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "mark list"),
+	)
+
+	markAll = key.NewBinding(
+		key.WithKeys("M"),
+		key.WithHelp("M", "mark all shown"),
+	)
+
+	invertMarks = key.NewBinding(
+		key.WithKeys("I"),
+		key.WithHelp("I", "invert marks"),
+	)
+
+	filterTrash = key.NewBinding(
+		key.WithKeys("F"),
+		key.WithHelp("F", "filter future mails to trash"),
+	)
+
+	reportSpam = key.NewBinding(
+		key.WithKeys("S"),
+		key.WithHelp("S", "report all mails as spam"),
+	)
+
+	switchSection = key.NewBinding(
+		key.WithKeys("tab"),
+		key.WithHelp("tab", "switch section"),
+	)
+
+	// G is left to the list, which jumps to its end with it
+	toggleGrouping = key.NewBinding(
+		key.WithKeys("B"),
+		key.WithHelp("B", "toggle grouping"),
+	)
+
+	nextCategory = key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "next category tab"),
+	)
+
+	toggleSort = key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "sort by unreads/size/engagement"),
+	)
+
+	toggleView = key.NewBinding(
+		key.WithKeys("v"),
+		key.WithHelp("v", "show lists/one-click/all senders"),
+	)
+
+	cycleActionAge = key.NewBinding(
+		key.WithKeys("A"),
+		key.WithHelp("A", "only act on mails older than 1w/1m/3m/1y"),
+	)
+
+	drillDown = key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "show senders or mails"),
+	)
+
+	deleteMessage = key.NewBinding(
+		key.WithKeys("D"),
+		key.WithHelp("D", "delete this mail"),
+	)
+
+	archiveMessage = key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "archive this mail"),
+	)
+
+	trashMessage = key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "trash this mail"),
+	)
+
+	scrollPreviewDown = key.NewBinding(
+		key.WithKeys("J"),
+		key.WithHelp("J", "scroll preview down"),
+	)
+
+	scrollPreviewUp = key.NewBinding(
+		key.WithKeys("K"),
+		key.WithHelp("K", "scroll preview up"),
+	)
+
+	showTemplates = key.NewBinding(
+		key.WithKeys("T"),
+		key.WithHelp("T", "show subject templates"),
+	)
+
+	drillUp = key.NewBinding(
+		key.WithKeys("esc", "backspace"),
+		key.WithHelp("esc", "back"),
+	)
+
+	confirmVisit = key.NewBinding(
+		key.WithKeys("g", "enter"),
+		key.WithHelp("g", "visit the link"),
+	)
+
+	confirmOpen = key.NewBinding(
+		key.WithKeys("o"),
+		key.WithHelp("o", "open in browser"),
+	)
+
+	confirmAction = key.NewBinding(
+		key.WithKeys("y", "enter"),
+		key.WithHelp("y", "confirm"),
+	)
+
+	confirmCancel = key.NewBinding(
+		key.WithKeys("esc", "n", "q"),
+		key.WithHelp("esc", "cancel"),
+	)
+
+	showOps = key.NewBinding(
+		key.WithKeys("O"),
+		key.WithHelp("O", "show operations"),
+	)
+
+	undoAction = key.NewBinding(
+		key.WithKeys("z"),
+		key.WithHelp("z", "undo"),
+	)
+
+	retryOp = key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "retry"),
+	)
+
+	retryLoading = key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "retry"),
+	)
+
+	reauthenticate = key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "sign in again"),
+	)
+
+	quitApp = key.NewBinding(
+		key.WithKeys("q", "ctrl+c"),
+		key.WithHelp("q", "quit"),
+	)
+
 	toggleHelpMenu = key.NewBinding(
 		key.WithKeys("H"),
 		key.WithHelp("H", "toggle help"),
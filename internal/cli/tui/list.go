@@ -1,6 +1,10 @@
 package tui
 
 import (
+	"fmt"
+	"io"
+	"slices"
+
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
@@ -9,43 +13,109 @@ import (
 
 type mailList struct {
 	list list.Model
+	// actions offered on the selected list
+	keys []key.Binding
+	// keys of the lists marked for a batch action, shared with the delegate
+	marked map[string]bool
 }
 
-func NewModel(mails []inbox.MailingList) mailList {
+func NewModel(mails []inbox.MailingList, view inbox.ViewMode) mailList {
+	items := make([]list.Item, 0, len(mails))
+	for _, m := range mails {
+		items = append(items, m)
+	}
+
+	title := "Mailing lists"
+	switch view {
+	case inbox.ViewOneClick:
+		title = "One-click mailing lists"
+	case inbox.ViewAllSenders:
+		title = "All senders"
+	}
+
+	return newMailList(title, items, []key.Binding{
+		unsubscribe,
+		deleteAll,
+		archiveAll,
+		trashAll,
+	})
+}
+
+// NewStillMailingModel lists senders that kept mailing after the user
+// unsubscribed, offering to escalate on them.
+func NewStillMailingModel(mails []inbox.MailingList) mailList {
 	items := make([]list.Item, 0, len(mails))
 	for _, m := range mails {
-		if !m.UnsubscribeAvailable() {
-			continue
-		}
 		items = append(items, m)
 	}
 
-	mailingList := list.New(items, list.NewDefaultDelegate(), 0, 0)
-	mailingList.Title = "Mailing lists"
+	return newMailList("Still mailing after unsubscribe", items, []key.Binding{
+		filterTrash,
+		reportSpam,
+		deleteAll,
+		archiveAll,
+		trashAll,
+	})
+}
+
+// NewMembersModel breaks a domain down into its senders, so actions can target
+// them one by one.
+func NewMembersModel(parent inbox.MailingList, escalate bool) mailList {
+	items := make([]list.Item, 0, len(parent.Members))
+	for _, m := range parent.Members {
+		items = append(items, m)
+	}
+
+	keys := []key.Binding{unsubscribe, deleteAll, archiveAll, trashAll, drillUp}
+	if escalate {
+		keys = []key.Binding{filterTrash, reportSpam, deleteAll, archiveAll, trashAll, drillUp}
+	}
+	return newMailList(fmt.Sprintf("Senders in %s", parent.Key), items, keys)
+}
+
+// NewTemplatesModel breaks a list down into its subject templates, so actions
+// can target one kind of mail without touching the rest. Unsubscribing and
+// filtering would hit the whole sender, so they are not offered.
+func NewTemplatesModel(parent inbox.MailingList, escalate bool) mailList {
+	items := make([]list.Item, 0, len(parent.Templates))
+	for _, t := range parent.Templates {
+		items = append(items, t)
+	}
+
+	keys := []key.Binding{deleteAll, archiveAll, trashAll, drillUp}
+	if escalate {
+		keys = []key.Binding{reportSpam, deleteAll, archiveAll, trashAll, drillUp}
+	}
+	return newMailList(fmt.Sprintf("Subjects from %s", parent.Key), items, keys)
+}
+
+func newMailList(title string, items []list.Item, keys []key.Binding) mailList {
+	marked := make(map[string]bool)
+	mailingList := list.New(items, markDelegate{DefaultDelegate: list.NewDefaultDelegate(), marked: marked}, 0, 0)
+	mailingList.Title = inbox.Sanitize(title)
 	mailingList.Styles.Title = titleStyle
 	mailingList.AdditionalShortHelpKeys = func() []key.Binding {
-		return []key.Binding{
-			unsubscribe,
-			deleteAll,
-			archiveAll,
-			trashAll,
-		}
+		return keys
 	}
 	mailingList.AdditionalFullHelpKeys = func() []key.Binding {
-		return []key.Binding{
-			unsubscribe,
-			deleteAll,
-			archiveAll,
-			trashAll,
-			toggleHelpMenu,
-		}
+		return append(append([]key.Binding{}, keys...), toggleMark, markAll, invertMarks, drillDown, showTemplates, switchSection, toggleGrouping, nextCategory, toggleSort, toggleView, cycleActionAge, showOps, undoAction, toggleHelpMenu)
 	}
 
 	return mailList{
-		list: mailingList,
+		list:   mailingList,
+		keys:   keys,
+		marked: marked,
 	}
 }
 
+// offers tells whether b is one of the actions of the list, which are told
+// apart by their help key.
+func (m mailList) offers(b key.Binding) bool {
+	return slices.ContainsFunc(m.keys, func(k key.Binding) bool {
+		return k.Help().Key == b.Help().Key
+	})
+}
+
 func (m mailList) Init() tea.Cmd {
 	return nil
 }
@@ -67,13 +137,37 @@ func (m mailList) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, toggleHelpMenu):
 			m.list.SetShowHelp(!m.list.ShowHelp())
 			return m, nil
-		case key.Matches(msg, unsubscribe):
+		case key.Matches(msg, toggleMark):
+			if mail, ok := m.getSelectedMail(); ok {
+				m.setMarked(mail.Key, !m.marked[mail.Key])
+				m.list.CursorDown()
+			}
+			return m, nil
+		case key.Matches(msg, markAll):
+			for _, item := range m.list.VisibleItems() {
+				if mail, ok := item.(inbox.MailingList); ok {
+					m.setMarked(mail.Key, true)
+				}
+			}
+			return m, nil
+		case key.Matches(msg, invertMarks):
+			for _, item := range m.list.VisibleItems() {
+				if mail, ok := item.(inbox.MailingList); ok {
+					m.setMarked(mail.Key, !m.marked[mail.Key])
+				}
+			}
+			return m, nil
+		case key.Matches(msg, unsubscribe) && m.offers(unsubscribe):
 			cmds = append(cmds, m.handleUnsubscribe()...)
-		case key.Matches(msg, deleteAll):
+		case key.Matches(msg, filterTrash) && m.offers(filterTrash):
+			cmds = append(cmds, m.handleFilterTrash()...)
+		case key.Matches(msg, reportSpam) && m.offers(reportSpam):
+			cmds = append(cmds, m.handleReportSpam()...)
+		case key.Matches(msg, deleteAll) && m.offers(deleteAll):
 			cmds = append(cmds, m.handleDeleteAll()...)
-		case key.Matches(msg, archiveAll):
+		case key.Matches(msg, archiveAll) && m.offers(archiveAll):
 			cmds = append(cmds, m.handleArchiveAll()...)
-		case key.Matches(msg, trashAll):
+		case key.Matches(msg, trashAll) && m.offers(trashAll):
 			cmds = append(cmds, m.handleTrashAll()...)
 		}
 	}
@@ -86,6 +180,10 @@ func (m mailList) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *mailList) handleUnsubscribe() []tea.Cmd {
+	if cmd := m.batchRequest(opUnsubscribe); cmd != nil {
+		return []tea.Cmd{cmd}
+	}
+
 	mail, ok := m.getSelectedMail()
 	if !ok {
 		return nil
@@ -95,13 +193,16 @@ func (m *mailList) handleUnsubscribe() []tea.Cmd {
 		func() tea.Msg {
 			return unsubscribeRequestMsg{
 				mail: mail,
-				idx:  m.list.Index(),
 			}
 		},
 	}
 }
 
 func (m *mailList) handleDeleteAll() []tea.Cmd {
+	if cmd := m.batchRequest(opDelete); cmd != nil {
+		return []tea.Cmd{cmd}
+	}
+
 	mail, ok := m.getSelectedMail()
 	if !ok {
 		return nil
@@ -111,13 +212,16 @@ func (m *mailList) handleDeleteAll() []tea.Cmd {
 		func() tea.Msg {
 			return deleteRequestMsg{
 				mail: mail,
-				idx:  m.list.Index(),
 			}
 		},
 	}
 }
 
 func (m *mailList) handleArchiveAll() []tea.Cmd {
+	if cmd := m.batchRequest(opArchive); cmd != nil {
+		return []tea.Cmd{cmd}
+	}
+
 	mail, ok := m.getSelectedMail()
 	if !ok {
 		return nil
@@ -127,13 +231,16 @@ func (m *mailList) handleArchiveAll() []tea.Cmd {
 		func() tea.Msg {
 			return archiveRequestMsg{
 				mail: mail,
-				idx:  m.list.Index(),
 			}
 		},
 	}
 }
 
 func (m *mailList) handleTrashAll() []tea.Cmd {
+	if cmd := m.batchRequest(opTrash); cmd != nil {
+		return []tea.Cmd{cmd}
+	}
+
 	mail, ok := m.getSelectedMail()
 	if !ok {
 		return nil
@@ -143,12 +250,71 @@ func (m *mailList) handleTrashAll() []tea.Cmd {
 		func() tea.Msg {
 			return trashRequestMsg{
 				mail: mail,
-				idx:  m.list.Index(),
 			}
 		},
 	}
 }
 
+func (m *mailList) handleFilterTrash() []tea.Cmd {
+	mail, ok := m.getSelectedMail()
+	if !ok {
+		return nil
+	}
+
+	return []tea.Cmd{
+		func() tea.Msg {
+			return filterRequestMsg{
+				mail: mail,
+			}
+		},
+	}
+}
+
+func (m *mailList) handleReportSpam() []tea.Cmd {
+	mail, ok := m.getSelectedMail()
+	if !ok {
+		return nil
+	}
+
+	return []tea.Cmd{
+		func() tea.Msg {
+			return spamRequestMsg{
+				mail: mail,
+			}
+		},
+	}
+}
+
+// batchRequest runs op across the marked lists, in the order they are shown,
+// or returns nil when none is marked.
+func (m *mailList) batchRequest(op opKind) tea.Cmd {
+	mails := m.markedMails()
+	if len(mails) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		return batchRequestMsg{op: op, mails: mails}
+	}
+}
+
+func (m *mailList) markedMails() []inbox.MailingList {
+	var mails []inbox.MailingList
+	for _, item := range m.list.Items() {
+		if mail, ok := item.(inbox.MailingList); ok && m.marked[mail.Key] {
+			mails = append(mails, mail)
+		}
+	}
+	return mails
+}
+
+func (m *mailList) setMarked(key string, marked bool) {
+	if marked {
+		m.marked[key] = true
+	} else {
+		delete(m.marked, key)
+	}
+}
+
 func (m *mailList) getSelectedMail() (inbox.MailingList, bool) {
 	mail, ok := m.list.SelectedItem().(inbox.MailingList)
 	return mail, ok
@@ -157,3 +323,25 @@ func (m *mailList) getSelectedMail() (inbox.MailingList, bool) {
 func (m mailList) View() string {
 	return appStyle.Render(m.list.View())
 }
+
+// markDelegate renders lists like the default delegate, flagging the marked
+// ones.
+type markDelegate struct {
+	list.DefaultDelegate
+	marked map[string]bool
+}
+
+func (d markDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	if mail, ok := item.(inbox.MailingList); ok && d.marked[mail.Key] {
+		item = markedItem{mail}
+	}
+	d.DefaultDelegate.Render(w, m, index, item)
+}
+
+type markedItem struct {
+	inbox.MailingList
+}
+
+func (i markedItem) Title() string {
+	return markStyle.Render("✓") + " " + i.MailingList.Title()
+}
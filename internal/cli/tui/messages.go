@@ -2,6 +2,7 @@ package tui
 
 import (
 	"github.com/sverdejot/geemail/internal/inbox"
+	"github.com/sverdejot/geemail/internal/ops"
 )
 
 // Mail streaming messages - emitted during mail loading
@@ -11,6 +12,8 @@ type mailReceivedMsg struct {
 
 type mailStreamReadyMsg struct {
 	stream <-chan inbox.RawMail
+	// unread mails in the inbox, for the progress bar
+	total int64
 }
 
 type mailStreamCompleteMsg struct{}
@@ -19,52 +22,85 @@ type mailStreamErrorMsg struct {
 	err error
 }
 
+type reauthenticatedMsg struct {
+	err error
+}
+
 // Intent messages - emitted by mailList when user takes action
 type unsubscribeRequestMsg struct {
 	mail inbox.MailingList
-	idx  int
 }
 
 type deleteRequestMsg struct {
 	mail inbox.MailingList
-	idx  int
 }
 
 type archiveRequestMsg struct {
 	mail inbox.MailingList
-	idx  int
 }
 
 type trashRequestMsg struct {
 	mail inbox.MailingList
-	idx  int
 }
 
-// Result messages - emitted after async operations complete
-type unsubscribeCompleteMsg struct {
+type filterRequestMsg struct {
 	mail inbox.MailingList
-	idx  int
-	err  error
 }
 
-type deleteCompleteMsg struct {
+type spamRequestMsg struct {
 	mail inbox.MailingList
-	idx  int
-	err  error
 }
 
-type archiveCompleteMsg struct {
-	mail inbox.MailingList
-	idx  int
-	err  error
+// batchRequestMsg runs op across every marked list
+type batchRequestMsg struct {
+	op    opKind
+	mails []inbox.MailingList
 }
 
-type trashCompleteMsg struct {
-	mail inbox.MailingList
-	idx  int
+// Confirmation messages - emitted by dialogs once the user answers
+type linkUnsubscribeConfirmedMsg struct {
+	mail    inbox.MailingList
+	browser bool
+}
+
+// actionConfirmedMsg queues op on mails once the user agreed to it
+type actionConfirmedMsg struct {
+	op    opKind
+	mails []inbox.MailingList
+	batch bool
+}
+
+type confirmCancelledMsg struct{}
+
+type batchSummaryClosedMsg struct{}
+
+// Operation queue messages
+type opUpdateMsg struct {
+	op ops.Operation
+}
+
+type opRetryMsg struct {
+	id int
+}
+
+type opsPanelClosedMsg struct{}
+
+// previewDueMsg fetches the body of a mail once the cursor stayed on it
+type previewDueMsg struct {
+	id string
+}
+
+type bodyFetchedMsg struct {
+	id   string
+	body string
 	err  error
 }
 
+type engagementMeasuredMsg struct {
+	scores map[string]inbox.Engagement
+	err    error
+}
+
 // Status message for user feedback
 type statusMsg struct {
 	text string
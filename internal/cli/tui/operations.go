@@ -0,0 +1,397 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sverdejot/geemail/internal/gmail"
+	"github.com/sverdejot/geemail/internal/inbox"
+	"github.com/sverdejot/geemail/internal/ops"
+	"github.com/sverdejot/geemail/internal/undo"
+)
+
+// opKind is an action the user can queue on a list.
+type opKind int
+
+const (
+	opUnsubscribe opKind = iota
+	opDelete
+	opArchive
+	opTrash
+	opFilter
+	opSpam
+	opUndo
+)
+
+var opKindNames = map[opKind]string{
+	opUnsubscribe: "unsubscribe",
+	opDelete:      "delete",
+	opArchive:     "archive",
+	opTrash:       "trash",
+	opFilter:      "filter",
+	opSpam:        "spam",
+	opUndo:        "undo",
+}
+
+func (k opKind) String() string {
+	return opKindNames[k]
+}
+
+// reversible tells whether the mails can be brought back from Gmail once
+// the action is done.
+func (k opKind) reversible() bool {
+	_, ok := k.labelDiff()
+	return ok
+}
+
+// labelDiff is what the action changes on the labels of its mails, for those
+// undone by changing them back.
+func (k opKind) labelDiff() (gmail.LabelDiff, bool) {
+	switch k {
+	case opArchive:
+		return gmail.ArchiveDiff, true
+	case opTrash:
+		return gmail.TrashDiff, true
+	case opSpam:
+		return gmail.SpamDiff, true
+	}
+	return gmail.LabelDiff{}, false
+}
+
+// tracked is what the TUI remembers of a queued operation to settle it.
+type tracked struct {
+	kind opKind
+	// the mails acted upon, possibly narrowed down by the age qualifier
+	mail inbox.MailingList
+	// for link unsubscribes, whether to open the page instead of visiting it
+	browser bool
+	// batch the operation belongs to, nil if queued on its own or retried
+	batch *batch
+	// the action being undone, for undo operations only
+	entry undo.Entry
+	// for deletes, whether they follow an unsubscribe from the same list
+	unsubscribed bool
+	// whether its failure was already handled, so it only waits for a retry
+	failed bool
+}
+
+// target names what the operation acts upon.
+func (t tracked) target() string {
+	if t.kind == opUndo {
+		return t.entry.Target
+	}
+	return t.mail.Key
+}
+
+// hides tells whether the mails leave the inbox, so they can be taken out of
+// the lists before the operation is even done. A link may land on a form the
+// user still has to fill in, so its mails are left alone.
+func (t tracked) hides() bool {
+	switch t.kind {
+	case opFilter, opUndo:
+		return false
+	case opUnsubscribe:
+		return t.mail.UnsubscribeMethod() != inbox.MethodLink && t.mail.TotalUnreads > 0
+	}
+	return true
+}
+
+func (t tracked) run(m *rootModel) func(context.Context) error {
+	mail := t.mail
+	return func(ctx context.Context) error {
+		switch t.kind {
+		case opUnsubscribe:
+			if t.browser {
+				return mail.OpenUnsubscribeLink()
+			}
+			// the mails are deleted by an operation of its own once this
+			// one succeeds, so a failed delete never unsubscribes twice
+			return mail.Unsubscribe(ctx)
+		case opDelete:
+			return m.svc.BulkDelete(ctx, mail.UnreadMessagesIDs)
+		case opArchive:
+			return m.svc.BulkArchive(ctx, mail.UnreadMessagesIDs)
+		case opTrash:
+			return m.svc.BulkTrash(ctx, mail.Messages)
+		case opFilter:
+			return m.svc.CreateTrashFilter(ctx, mail.From, mail.ListID)
+		case opSpam:
+			return m.svc.ReportSpam(ctx, mail.UnreadMessagesIDs)
+		case opUndo:
+			return m.svc.Revert(ctx, t.entry.LabelChanges())
+		}
+		return fmt.Errorf("unknown operation %s", t.kind)
+	}
+}
+
+// done describes a successful operation.
+func (t tracked) done() string {
+	mail := t.mail
+	switch t.kind {
+	case opUnsubscribe:
+		if mail.UnsubscribeMethod() == inbox.MethodLink {
+			if t.browser {
+				return fmt.Sprintf("Opened unsubscribe page for %s in the browser", mail.From)
+			}
+			attempt, _ := mail.LastUnsubscribeAttempt()
+			return fmt.Sprintf("Visited unsubscribe page for %s: %s", mail.From, attempt.Detail)
+		}
+		return fmt.Sprintf("Unsubscribed from %s via %s", mail.From, mail.UnsubscribeMethod())
+	case opDelete:
+		if t.unsubscribed {
+			return fmt.Sprintf("Unsubscribed from %s via %s and deleted (%d) mails", mail.From, mail.UnsubscribeMethod(), mail.TotalUnreads)
+		}
+		return fmt.Sprintf("Deleted (%d) mails from %s, freeing ~%s", mail.TotalUnreads, mail.From, inbox.FormatBytes(mail.TotalBytes))
+	case opArchive:
+		return fmt.Sprintf("Archived (%d) mails from %s", mail.TotalUnreads, mail.From)
+	case opTrash:
+		return fmt.Sprintf("Trashed (%d) mails from %s, freeing ~%s once the trash is emptied", mail.TotalUnreads, mail.From, inbox.FormatBytes(mail.TotalBytes))
+	case opFilter:
+		return fmt.Sprintf("Future mails from %s will go straight to trash", mail.From)
+	case opSpam:
+		return fmt.Sprintf("Reported (%d) mails from %s as spam", mail.TotalUnreads, mail.From)
+	case opUndo:
+		return fmt.Sprintf("Undid %s of (%d) mails from %s", t.entry.Action, len(t.entry.IDs()), t.entry.Target)
+	}
+	return ""
+}
+
+// listenOps waits for the next change in the operation queue.
+func (m *rootModel) listenOps() tea.Cmd {
+	return func() tea.Msg {
+		return opUpdateMsg{op: <-m.queue.Updates()}
+	}
+}
+
+// enqueue submits an operation, taking its mails out of the lists right away
+// when it will remove them from the inbox.
+func (m *rootModel) enqueue(t tracked) tea.Cmd {
+	if t.hides() {
+		m.hide(t.mail)
+	}
+	op := m.queue.Submit(t.kind.String(), t.target(), t.run(m))
+	m.tracked[op.ID] = t
+	m.dropPruned()
+	if t.batch != nil {
+		return nil
+	}
+	return m.statusCmd(fmt.Sprintf("Queued %s of %s, %d operations pending", t.kind, t.target(), m.queue.Active()))
+}
+
+// settle reacts to an operation being done: successful ones are final, failed
+// ones bring their mails back so they can be retried.
+func (m *rootModel) settle(op ops.Operation) tea.Cmd {
+	t, ok := m.tracked[op.ID]
+	if !ok {
+		return nil
+	}
+
+	if t.kind == opUndo {
+		return m.settleUndo(op, t)
+	}
+
+	var cmds []tea.Cmd
+	if op.Status == ops.Succeeded && t.kind == opUnsubscribe && t.hides() {
+		return m.settleUnsubscribe(op, t)
+	}
+	if op.Status == ops.Succeeded {
+		delete(m.tracked, op.ID)
+		var gone []inbox.RawMail
+		if t.hides() {
+			gone = m.forget(t.mail.UnreadMessagesIDs)
+		}
+		text := t.done()
+		if diff, ok := t.kind.labelDiff(); ok && m.undo != nil {
+			// only what each mail lost is given back, mails already out of
+			// the inbox stay out of it
+			changes := gmail.Changes(t.mail.Messages, diff)
+			if err := m.undo.Push(undo.NewEntry(t.kind.String(), t.mail.Key, changes)); err != nil {
+				text = fmt.Sprintf("%s, but it cannot be undone: %s", text, err)
+			}
+			for _, rm := range gone {
+				m.restorable[rm.ID] = rm
+			}
+		}
+		// opening the page is no proof the user went through with it, so
+		// the list is not expected to stop mailing
+		if t.kind == opUnsubscribe && !t.browser {
+			text = m.recordUnsubscribe(t, text)
+		}
+		if t.batch == nil {
+			cmds = append(cmds, m.statusCmd(text))
+		} else {
+			cmds = append(cmds, m.recordBatchResult(t.batch, batchResult{mail: t.mail, detail: text}))
+		}
+		return tea.Batch(cmds...)
+	}
+
+	if t.hides() {
+		cmds = append(cmds, m.unhide(t.mail.UnreadMessagesIDs))
+	}
+	err := op.Err
+	if t.unsubscribed {
+		err = fmt.Errorf("unsubscribed but could not delete mails: %w", err)
+	}
+	if t.batch == nil {
+		switch {
+		case t.kind == opUnsubscribe:
+			cmds = append(cmds, m.handleUnsubscribeError(t.mail, err))
+		case t.unsubscribed:
+			cmds = append(cmds, m.statusCmd(fmt.Sprintf("Error deleting mails from %s, press O to retry: %s", t.target(), err)))
+		default:
+			cmds = append(cmds, m.statusCmd(fmt.Sprintf("Error running %s on %s, press O to retry: %s", t.kind, t.target(), err)))
+		}
+	} else {
+		cmds = append(cmds, m.recordBatchResult(t.batch, batchResult{mail: t.mail, err: err}))
+		// a retry is settled on its own, the batch summary is long gone
+		t.batch = nil
+	}
+	t.failed = true
+	m.tracked[op.ID] = t
+	return tea.Batch(cmds...)
+}
+
+// settleUnsubscribe records a successful unsubscribe and queues the delete
+// of its mails, which stay hidden meanwhile. The batch, if any, hears of the
+// list once they are deleted.
+func (m *rootModel) settleUnsubscribe(op ops.Operation, t tracked) tea.Cmd {
+	delete(m.tracked, op.ID)
+	text := m.recordUnsubscribe(t, t.done())
+	cmd := m.enqueue(tracked{kind: opDelete, mail: t.mail, batch: t.batch, unsubscribed: true})
+	if t.batch != nil {
+		return cmd
+	}
+	return m.statusCmd(fmt.Sprintf("%s, deleting its mails", text))
+}
+
+// recordUnsubscribe notes a successful unsubscribe in the journal, telling
+// in text if it could not.
+func (m *rootModel) recordUnsubscribe(t tracked, text string) string {
+	if m.journal == nil {
+		return text
+	}
+	attempt, ok := t.mail.LastUnsubscribeAttempt()
+	if !ok {
+		return text
+	}
+	if err := m.journal.Record(t.mail, attempt); err != nil {
+		return fmt.Sprintf("%s, but could not record it: %s", text, err)
+	}
+	return text
+}
+
+// dropPruned forgets failed operations the queue no longer keeps, as they
+// cannot be retried anymore. Those not settled yet are kept until they are,
+// as their mails may still have to be brought back.
+func (m *rootModel) dropPruned() {
+	for id, t := range m.tracked {
+		if t.failed && !m.queue.Has(id) {
+			delete(m.tracked, id)
+		}
+	}
+}
+
+// retry queues a failed operation again, hiding its mails once more.
+func (m *rootModel) retry(id int) tea.Cmd {
+	t, ok := m.tracked[id]
+	if !ok {
+		return m.statusCmd("This operation can no longer be retried")
+	}
+	if _, err := m.queue.Retry(id); err != nil {
+		return m.statusCmd(fmt.Sprintf("Cannot retry %s of %s: %s", t.kind, t.target(), err))
+	}
+	t.failed = false
+	m.tracked[id] = t
+	if t.hides() {
+		m.hide(t.mail)
+	}
+	return m.statusCmd(fmt.Sprintf("Retrying %s of %s", t.kind, t.target()))
+}
+
+// undoLast queues the undo of the latest reversible action, which may come
+// from an earlier session.
+func (m *rootModel) undoLast() tea.Cmd {
+	if m.undo == nil || m.undo.Len() == 0 {
+		return m.statusCmd("Nothing to undo")
+	}
+	if m.dryRun {
+		return m.statusCmd("[DRY RUN] Would undo the latest action")
+	}
+	e, ok, err := m.undo.Pop()
+	if err != nil {
+		return m.statusCmd(fmt.Sprintf("Cannot undo: %s", err))
+	}
+	if !ok {
+		return m.statusCmd("Nothing to undo")
+	}
+	return m.enqueue(tracked{kind: opUndo, entry: e})
+}
+
+// settleUndo shows the restored mails again when they were scanned in this
+// session. A failed undo goes back on the stack to be tried again with z,
+// rather than from the operations panel, so it is never undone twice.
+func (m *rootModel) settleUndo(op ops.Operation, t tracked) tea.Cmd {
+	delete(m.tracked, op.ID)
+	if op.Status != ops.Succeeded {
+		text := fmt.Sprintf("Error undoing %s of %s, press z to try again: %s", t.entry.Action, t.entry.Target, op.Err)
+		if err := m.undo.Push(t.entry); err != nil {
+			text = fmt.Sprintf("Error undoing %s of %s: %s", t.entry.Action, t.entry.Target, op.Err)
+		}
+		return m.statusCmd(text)
+	}
+
+	var restored bool
+	for _, id := range t.entry.IDs() {
+		if rm, ok := m.restorable[id]; ok {
+			m.mails = append(m.mails, rm)
+			delete(m.restorable, id)
+			restored = true
+		}
+	}
+	if !restored {
+		return m.statusCmd(t.done())
+	}
+	return tea.Batch(m.buildLists(), m.statusCmd(t.done()))
+}
+
+// hide takes mails out of every list, and keeps them out should the lists be
+// built again while their operation is pending.
+func (m *rootModel) hide(mail inbox.MailingList) {
+	for _, id := range mail.UnreadMessagesIDs {
+		m.hidden[id] = true
+	}
+	m.removeHandled(mail)
+	m.unmark(mail.Key)
+	if m.detail != nil {
+		m.detail.remove(mail.UnreadMessagesIDs)
+	}
+}
+
+// forget drops mails that left the inbox, returning them.
+func (m *rootModel) forget(ids []string) []inbox.RawMail {
+	gone := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		gone[id] = true
+		delete(m.hidden, id)
+	}
+	var dropped []inbox.RawMail
+	m.mails = slices.DeleteFunc(m.mails, func(rm inbox.RawMail) bool {
+		if gone[rm.ID] {
+			dropped = append(dropped, rm)
+			return true
+		}
+		return false
+	})
+	return dropped
+}
+
+// unhide brings back mails whose operation failed. Lists are built again, as
+// the ones they were taken out of may have changed since.
+func (m *rootModel) unhide(ids []string) tea.Cmd {
+	for _, id := range ids {
+		delete(m.hidden, id)
+	}
+	return m.buildLists()
+}
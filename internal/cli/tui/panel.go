@@ -0,0 +1,98 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sverdejot/geemail/internal/inbox"
+	"github.com/sverdejot/geemail/internal/ops"
+)
+
+// at most this many operations are listed, the newest ones
+const panelMaxLines = 15
+
+var (
+	panelUp = key.NewBinding(
+		key.WithKeys("up", "k"),
+	)
+
+	panelDown = key.NewBinding(
+		key.WithKeys("down", "j"),
+	)
+)
+
+// opsPanel lists the recent operations, letting the user retry failed ones.
+type opsPanel struct {
+	cursor int
+}
+
+func (p *opsPanel) Update(msg tea.KeyMsg, recent []ops.Operation) tea.Cmd {
+	recent = recent[:min(len(recent), panelMaxLines)]
+	switch {
+	case key.Matches(msg, panelUp):
+		p.cursor = max(p.cursor-1, 0)
+	case key.Matches(msg, panelDown):
+		p.cursor = min(p.cursor+1, max(len(recent)-1, 0))
+	case key.Matches(msg, retryOp):
+		if p.cursor < len(recent) && recent[p.cursor].Status == ops.Failed {
+			id := recent[p.cursor].ID
+			return func() tea.Msg {
+				return opRetryMsg{id: id}
+			}
+		}
+	case key.Matches(msg, confirmCancel), key.Matches(msg, showOps):
+		return func() tea.Msg {
+			return opsPanelClosedMsg{}
+		}
+	}
+	return nil
+}
+
+func (p *opsPanel) View(recent []ops.Operation) string {
+	if len(recent) == 0 {
+		return dialogStyle.Render(lipgloss.JoinVertical(lipgloss.Left,
+			"No operations yet\n",
+			dialogHelpStyle.Render(helpText(confirmCancel)),
+		))
+	}
+
+	recent = recent[:min(len(recent), panelMaxLines)]
+	lines := make([]string, 0, len(recent))
+	for i, op := range recent {
+		line := fmt.Sprintf("%s %s %s", statusIcon(op.Status), op.Name, inbox.Sanitize(op.Target))
+		if op.Attempts > 1 {
+			line += fmt.Sprintf(" (attempt %d)", op.Attempts)
+		}
+		if op.Err != nil {
+			line = warningStyle.Render(line + ": " + inbox.Sanitize(op.Err.Error()))
+		}
+		if i == p.cursor {
+			line = markStyle.Render("> ") + line
+		} else {
+			line = "  " + line
+		}
+		lines = append(lines, line)
+	}
+
+	return dialogStyle.Render(lipgloss.JoinVertical(lipgloss.Left,
+		"Operations\n",
+		strings.Join(lines, "\n"),
+		"\n"+dialogHelpStyle.Render(fmt.Sprintf("%s failed • %s", helpText(retryOp), helpText(confirmCancel))),
+	))
+}
+
+func statusIcon(s ops.Status) string {
+	switch s {
+	case ops.Running:
+		return "◐"
+	case ops.Succeeded:
+		return "✓"
+	case ops.Failed:
+		return "✗"
+	default:
+		return "◌"
+	}
+}
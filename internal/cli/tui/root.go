@@ -2,12 +2,22 @@ package tui
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/sverdejot/geemail/internal/gmail"
 	"github.com/sverdejot/geemail/internal/inbox"
+	"github.com/sverdejot/geemail/internal/journal"
+	"github.com/sverdejot/geemail/internal/ops"
+	"github.com/sverdejot/geemail/internal/undo"
 )
 
 type state int
@@ -15,31 +25,106 @@ type state int
 const (
 	loading state = iota
 	ready
+	// loading stopped on an error, shown until the user retries or quits
+	failed
 )
 
-type rootModel struct {
-	state               state
-	progress            *mailLoadingProgress
-	list                mailList
-	ctx                 context.Context
-	svc                 *gmail.MailService
-	mails               []inbox.RawMail
-	mailStream          <-chan inbox.RawMail
-	width               int
-	height              int
-	dryRun              bool
-	operationInProgress bool
-	currentOperation    string
+type section int
+
+const (
+	mailingListsSection section = iota
+	stillMailingSection
+)
+
+// Config holds the user preferences for a TUI session.
+type Config struct {
+	DryRun bool
+	// where successful unsubscribes are recorded, may be nil
+	Journal *journal.Journal
+	// where reversible actions are recorded to be undone, may be nil
+	Undo    *undo.Stack
+	GroupBy inbox.GroupKey
+	// inbox tab to start on, all of them if unset
+	Category inbox.Category
+	SortBy   inbox.SortOrder
+	View     inbox.ViewMode
+	// how far back to look for read mails when measuring engagement, which
+	// is skipped when zero
+	EngagementWindow time.Duration
+	// only group mails received within this window, any age when zero
+	OlderThan, NewerThan time.Duration
+	// how many operations may run at once
+	Concurrency int
+	// permanent deletes of more mails than this need the count typed in
+	ConfirmThreshold int
+	// queue archive, trash and spam reports without asking first
+	SkipReversibleConfirm bool
+	// signs in again and swaps the credentials of the service, nil when that
+	// cannot be done from the TUI, as with service accounts
+	Reauthenticate func(context.Context) error
 }
 
-func NewRoot(ctx context.Context, svc *gmail.MailService, dryRun bool) (*rootModel, error) {
-	total, err := svc.GetTotalUnreads(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("cannot get total unread messages: %w", err)
-	}
+// age qualifiers actions cycle through, zero meaning every mail
+var actionAges = []time.Duration{0, 7 * 24 * time.Hour, 30 * 24 * time.Hour, 90 * 24 * time.Hour, 365 * 24 * time.Hour}
 
+type rootModel struct {
+	state        state
+	progress     *mailLoadingProgress
+	list         mailList
+	stillMailing mailList
+	section      section
+	// senders or subject templates of the list being drilled into, nil when
+	// not drilling
+	drill            *mailList
+	drillParent      inbox.MailingList
+	ctx              context.Context
+	svc              *gmail.MailService
+	mails            []inbox.RawMail
+	mailStream       <-chan inbox.RawMail
+	width            int
+	height           int
+	dryRun           bool
+	journal          *journal.Journal
+	undo             *undo.Stack
+	groupBy          inbox.GroupKey
+	category         inbox.Category
+	sortBy           inbox.SortOrder
+	view             inbox.ViewMode
+	engagementWindow time.Duration
+	engagement       map[string]inbox.Engagement
+	olderThan        time.Duration
+	newerThan        time.Duration
+	confirm          *linkConfirm
+	action           *actionConfirm
+	summary          *batchSummary
+	panel            *opsPanel
+	// mails of the list being looked into, nil when not shown
+	detail *detailModel
+	queue  *ops.Queue
+	// queued operations not settled for good, by ID
+	tracked map[int]tracked
+	// IDs of the mails taken out of the lists while their operation runs
+	hidden map[string]bool
+	// mails moved out of the inbox by reversible actions this session, by
+	// ID, to be shown again when undone
+	restorable map[string]inbox.RawMail
+	// actions only touch mails older than this, all of them when zero
+	actionAge             time.Duration
+	confirmThreshold      int
+	skipReversibleConfirm bool
+	// why loading failed, kept to exit with once the program is done
+	err              error
+	reauth           func(context.Context) error
+	reauthenticating bool
+	// why signing in again failed, shown along with err
+	reauthErr error
+}
+
+// NewRoot sets the TUI up without calling the API, so that even the first
+// call failing, as with expired credentials, lands on the error screen.
+func NewRoot(ctx context.Context, svc *gmail.MailService, cfg Config) *rootModel {
 	mails := make([]inbox.RawMail, 0)
-	pg := NewProgressModel(total)
+	pg := NewProgressModel(0)
 
 	return &rootModel{
 		state:    loading,
@@ -47,25 +132,54 @@ func NewRoot(ctx context.Context, svc *gmail.MailService, dryRun bool) (*rootMod
 		ctx:      ctx,
 		svc:      svc,
 		mails:    mails,
-		dryRun:   dryRun,
-	}, nil
+		dryRun:   cfg.DryRun,
+		journal:  cfg.Journal,
+		undo:     cfg.Undo,
+		groupBy:  cfg.GroupBy,
+		category: cfg.Category,
+		sortBy:   cfg.SortBy,
+		view:     cfg.View,
+
+		engagementWindow: cfg.EngagementWindow,
+		olderThan:        cfg.OlderThan,
+		newerThan:        cfg.NewerThan,
+		queue:            ops.NewQueue(ctx, ops.WithConcurrency(cfg.Concurrency)),
+		tracked:          make(map[int]tracked),
+		hidden:           make(map[string]bool),
+		restorable:       make(map[string]inbox.RawMail),
+
+		confirmThreshold:      cfg.ConfirmThreshold,
+		skipReversibleConfirm: cfg.SkipReversibleConfirm,
+		reauth:                cfg.Reauthenticate,
+	}
 }
 
 func (m *rootModel) Init() tea.Cmd {
 	return tea.Batch(
 		m.progress.Init(),
 		m.startLoading(),
+		m.listenOps(),
 	)
 }
 
+// Err is why the program stopped, nil when the user quit on their own.
+func (m *rootModel) Err() error {
+	return m.err
+}
+
 func (m *rootModel) startLoading() tea.Cmd {
 	return func() tea.Msg {
+		total, err := m.svc.GetTotalUnreads(m.ctx)
+		if err != nil {
+			return mailStreamErrorMsg{err: fmt.Errorf("cannot get total unread messages: %w", err)}
+		}
+
 		stream, err := m.svc.StreamUnreadMessages(m.ctx)
 		if err != nil {
 			return mailStreamErrorMsg{err: err}
 		}
 
-		return mailStreamReadyMsg{stream: stream}
+		return mailStreamReadyMsg{stream: stream, total: total}
 	}
 }
 
@@ -90,12 +204,15 @@ func (m *rootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.state == loading {
 			_, cmd := m.progress.Update(msg)
 			cmds = append(cmds, cmd)
-		} else {
-			updatedModel, cmd := m.list.Update(msg)
-			if updatedList, ok := updatedModel.(mailList); ok {
-				m.list = updatedList
+		} else if m.state == ready {
+			size := m.listSize()
+			cmds = append(cmds, m.updateList(&m.list, size), m.updateList(&m.stillMailing, size))
+			if m.drill != nil {
+				cmds = append(cmds, m.updateList(m.drill, size))
+			}
+			if m.detail != nil {
+				m.detail.SetSize(size.Width, size.Height)
 			}
-			cmds = append(cmds, cmd)
 		}
 		return m, tea.Batch(cmds...)
 
@@ -103,6 +220,7 @@ func (m *rootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.state == loading {
 			// Store the stream and start reading from it
 			m.mailStream = msg.stream
+			m.progress.total = msg.total
 			return m, m.readNextMail()
 		}
 
@@ -122,8 +240,17 @@ func (m *rootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case mailStreamErrorMsg:
-		// Handle stream error (could show error message or quit)
-		return m, tea.Quit
+		m.state = failed
+		m.err = fmt.Errorf("cannot load unread mails: %w", msg.err)
+		return m, nil
+
+	case reauthenticatedMsg:
+		m.reauthenticating = false
+		m.reauthErr = msg.err
+		if msg.err != nil {
+			return m, nil
+		}
+		return m, m.reload()
 
 	case progressMsg:
 		if m.state == loading {
@@ -134,16 +261,20 @@ func (m *rootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case endMsg:
 		if m.state == loading {
-			rawMailList := inbox.RawMailList(m.mails)
-			mailingLists := inbox.GetMailingList(rawMailList)
-
-			m.list = NewModel(mailingLists)
-			if m.width > 0 && m.height > 0 {
-				updatedModel, sizeCmd := m.list.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
-				if updatedList, ok := updatedModel.(mailList); ok {
-					m.list = updatedList
-				}
-				cmds = append(cmds, sizeCmd)
+			cmds = append(cmds, m.buildLists())
+			// both fit on the status line, one would replace the other
+			var notices []string
+			if n := len(m.stillMailing.list.Items()); n > 0 {
+				notices = append(notices, fmt.Sprintf("%d lists kept mailing after unsubscribing, press tab to review them", n))
+			}
+			if w := inbox.CountParseWarnings(m.mails); w.Total() > 0 {
+				notices = append(notices, fmt.Sprintf("Some headers were malformed and had to be guessed: %s", w))
+			}
+			if len(notices) > 0 {
+				cmds = append(cmds, m.statusCmd(strings.Join(notices, ". ")))
+			}
+			if m.engagementWindow > 0 {
+				cmds = append(cmds, m.measureEngagement())
 			}
 			m.state = ready
 
@@ -153,157 +284,226 @@ func (m *rootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case unsubscribeRequestMsg:
-		if m.operationInProgress {
-			return m, m.statusCmd(fmt.Sprintf("Operation '%s' already in progress...", m.currentOperation))
+		if !msg.mail.UnsubscribeAvailable() {
+			return m, m.handleUnsubscribeError(msg.mail, inbox.ErrNoUnsubscriber)
 		}
 
+		// unsubscribing is still worth it when no mail is old enough to be
+		// deleted afterwards
+		mail, _ := m.actionTarget(msg.mail)
+
 		if m.dryRun {
-			return m, m.statusCmd(fmt.Sprintf("[DRY RUN] Would unsubscribe from %s", msg.mail.From))
+			return m, m.statusCmd(fmt.Sprintf("[DRY RUN] Would unsubscribe from %s using %s", mail.From, mail.UnsubscribeMethod()))
 		}
 
-		m.operationInProgress = true
-		m.currentOperation = "unsubscribe"
-
-		return m, func() tea.Msg {
-			err := msg.mail.Unsubscribe(m.ctx)
-			return unsubscribeCompleteMsg{
-				mail: msg.mail,
-				idx:  msg.idx,
-				err:  err,
-			}
+		if mail.UnsubscribeMethod() == inbox.MethodLink {
+			m.confirm = newLinkConfirm(mail)
+			return m, nil
 		}
 
-	case unsubscribeCompleteMsg:
-		m.operationInProgress = false
-		m.currentOperation = ""
-
-		if msg.err != nil {
-			return m, m.handleUnsubscribeError(msg.mail, msg.err)
+		if mail.TotalUnreads == 0 {
+			return m, m.enqueue(tracked{kind: opUnsubscribe, mail: mail})
 		}
+		return m, m.ask(opUnsubscribe, []inbox.MailingList{mail}, false)
 
-		// Unsubscribe succeeded, now delete the emails
-		m.operationInProgress = true
-		m.currentOperation = "delete"
+	case linkUnsubscribeConfirmedMsg:
+		m.confirm = nil
+		return m, m.enqueue(tracked{kind: opUnsubscribe, mail: msg.mail, browser: msg.browser})
 
-		return m, func() tea.Msg {
-			err := m.svc.BulkDelete(m.ctx, msg.mail.UnreadMessagesIDs)
-			return deleteCompleteMsg{
-				mail: msg.mail,
-				idx:  msg.idx,
-				err:  err,
-			}
-		}
+	case actionConfirmedMsg:
+		m.action = nil
+		return m, m.proceed(msg.op, msg.mails, msg.batch)
+
+	case confirmCancelledMsg:
+		m.confirm = nil
+		m.action = nil
+		return m, nil
 
 	case deleteRequestMsg:
-		if m.operationInProgress {
-			return m, m.statusCmd(fmt.Sprintf("Operation '%s' already in progress...", m.currentOperation))
+		mail, ok := m.actionTarget(msg.mail)
+		if !ok {
+			return m, m.noTargetCmd(msg.mail)
 		}
 
 		if m.dryRun {
-			return m, m.statusCmd(fmt.Sprintf("[DRY RUN] Would delete (%d) mails from %s", msg.mail.TotalUnreads, msg.mail.From))
+			return m, m.statusCmd(fmt.Sprintf("[DRY RUN] Would delete (%d) mails from %s, freeing ~%s", mail.TotalUnreads, mail.From, inbox.FormatBytes(mail.TotalBytes)))
 		}
 
-		m.operationInProgress = true
-		m.currentOperation = "delete"
+		return m, m.ask(opDelete, []inbox.MailingList{mail}, false)
 
-		return m, func() tea.Msg {
-			err := m.svc.BulkDelete(m.ctx, msg.mail.UnreadMessagesIDs)
-			return deleteCompleteMsg{
-				mail: msg.mail,
-				idx:  msg.idx,
-				err:  err,
-			}
+	case archiveRequestMsg:
+		mail, ok := m.actionTarget(msg.mail)
+		if !ok {
+			return m, m.noTargetCmd(msg.mail)
 		}
 
-	case deleteCompleteMsg:
-		m.operationInProgress = false
-		m.currentOperation = ""
-
-		if msg.err != nil {
-			return m, m.statusCmd(fmt.Sprintf("Error deleting (%d) mails from %s. Please, try again later.", msg.mail.TotalUnreads, msg.mail.From))
+		if m.dryRun {
+			return m, m.statusCmd(fmt.Sprintf("[DRY RUN] Would archive (%d) mails from %s", mail.TotalUnreads, mail.From))
 		}
 
-		m.list.list.RemoveItem(msg.idx)
-		return m, m.statusCmd(fmt.Sprintf("Deleted (%d) mails from %s", msg.mail.TotalUnreads, msg.mail.From))
+		return m, m.ask(opArchive, []inbox.MailingList{mail}, false)
 
-	case archiveRequestMsg:
-		if m.operationInProgress {
-			return m, m.statusCmd(fmt.Sprintf("Operation '%s' already in progress...", m.currentOperation))
+	case trashRequestMsg:
+		mail, ok := m.actionTarget(msg.mail)
+		if !ok {
+			return m, m.noTargetCmd(msg.mail)
 		}
 
 		if m.dryRun {
-			return m, m.statusCmd(fmt.Sprintf("[DRY RUN] Would archive (%d) mails from %s", msg.mail.TotalUnreads, msg.mail.From))
+			return m, m.statusCmd(fmt.Sprintf("[DRY RUN] Would trash (%d) mails from %s, freeing ~%s once the trash is emptied", mail.TotalUnreads, mail.From, inbox.FormatBytes(mail.TotalBytes)))
 		}
 
-		m.operationInProgress = true
-		m.currentOperation = "archive"
+		return m, m.ask(opTrash, []inbox.MailingList{mail}, false)
 
-		return m, func() tea.Msg {
-			err := m.svc.BulkArchive(m.ctx, msg.mail.UnreadMessagesIDs)
-			return archiveCompleteMsg{
-				mail: msg.mail,
-				idx:  msg.idx,
-				err:  err,
-			}
+	case filterRequestMsg:
+		if m.dryRun {
+			return m, m.statusCmd(fmt.Sprintf("[DRY RUN] Would filter future mails from %s to trash", msg.mail.From))
 		}
 
-	case archiveCompleteMsg:
-		m.operationInProgress = false
-		m.currentOperation = ""
+		return m, m.ask(opFilter, []inbox.MailingList{msg.mail}, false)
 
-		if msg.err != nil {
-			return m, m.statusCmd(fmt.Sprintf("Error archiving (%d) mails from %s. Please, try again later.", msg.mail.TotalUnreads, msg.mail.From))
+	case spamRequestMsg:
+		mail, ok := m.actionTarget(msg.mail)
+		if !ok {
+			return m, m.noTargetCmd(msg.mail)
 		}
 
-		m.list.list.RemoveItem(msg.idx)
-		return m, m.statusCmd(fmt.Sprintf("Archived (%d) mails from %s", msg.mail.TotalUnreads, msg.mail.From))
-
-	case trashRequestMsg:
-		if m.operationInProgress {
-			return m, m.statusCmd(fmt.Sprintf("Operation '%s' already in progress...", m.currentOperation))
+		if m.dryRun {
+			return m, m.statusCmd(fmt.Sprintf("[DRY RUN] Would report (%d) mails from %s as spam", mail.TotalUnreads, mail.From))
 		}
 
+		return m, m.ask(opSpam, []inbox.MailingList{mail}, false)
+
+	case batchRequestMsg:
 		if m.dryRun {
-			return m, m.statusCmd(fmt.Sprintf("[DRY RUN] Would trash (%d) mails from %s", msg.mail.TotalUnreads, msg.mail.From))
+			var total int
+			for _, mail := range msg.mails {
+				if target, ok := m.actionTarget(mail); ok {
+					total += target.TotalUnreads
+				}
+			}
+			return m, m.statusCmd(fmt.Sprintf("[DRY RUN] Would %s %d lists, touching (%d) mails", msg.op, len(msg.mails), total))
 		}
 
-		m.operationInProgress = true
-		m.currentOperation = "trash"
+		return m, m.ask(msg.op, msg.mails, true)
 
-		return m, func() tea.Msg {
-			err := m.svc.BulkTrash(m.ctx, msg.mail.UnreadMessagesIDs)
-			return trashCompleteMsg{
-				mail: msg.mail,
-				idx:  msg.idx,
-				err:  err,
-			}
+	case batchSummaryClosedMsg:
+		m.summary = nil
+		return m, nil
+
+	case opUpdateMsg:
+		cmds = append(cmds, m.listenOps())
+		if msg.op.Status.Done() {
+			cmds = append(cmds, m.settle(msg.op))
 		}
+		return m, tea.Batch(cmds...)
 
-	case trashCompleteMsg:
-		m.operationInProgress = false
-		m.currentOperation = ""
+	case opRetryMsg:
+		return m, m.retry(msg.id)
 
-		if msg.err != nil {
-			return m, m.statusCmd(fmt.Sprintf("Error trashing (%d) mails from %s. Please, try again later.", msg.mail.TotalUnreads, msg.mail.From))
+	case opsPanelClosedMsg:
+		m.panel = nil
+		return m, nil
+
+	case engagementMeasuredMsg:
+		var partial *gmail.EngagementError
+		if msg.err != nil && !errors.As(msg.err, &partial) {
+			return m, m.statusCmd(fmt.Sprintf("Error measuring engagement: %s", msg.err))
 		}
 
-		m.list.list.RemoveItem(msg.idx)
-		return m, m.statusCmd(fmt.Sprintf("Trashed (%d) mails from %s", msg.mail.TotalUnreads, msg.mail.From))
+		m.engagement = msg.scores
+		text := fmt.Sprintf("Measured engagement for %d senders", len(msg.scores))
+		if partial != nil {
+			text = fmt.Sprintf("%s, %d failed: %s", text, partial.Failed, partial.Err)
+		}
+		return m, tea.Batch(m.applyEngagement(), m.statusCmd(text))
+
+	case bodyFetchedMsg, previewDueMsg:
+		if m.detail != nil {
+			return m, m.detail.Update(msg)
+		}
+		return m, nil
 
 	case statusMsg:
-		updatedModel, cmd := m.list.Update(m.list.list.NewStatusMessage(msg.text))
-		if updatedList, ok := updatedModel.(mailList); ok {
-			m.list = updatedList
+		if m.detail != nil {
+			return m, m.detail.list.NewStatusMessage(msg.text)
 		}
-		return m, cmd
+		l := m.activeList()
+		return m, m.updateList(l, l.list.NewStatusMessage(msg.text))
 
 	case tea.KeyMsg:
+		if m.state == failed {
+			return m, m.updateFailed(msg)
+		}
+		if m.action != nil {
+			return m, m.action.Update(msg)
+		}
+		if m.confirm != nil {
+			return m, m.confirm.Update(msg)
+		}
+		if m.summary != nil {
+			return m, m.summary.Update(msg)
+		}
+		if m.panel != nil {
+			return m, m.panel.Update(msg, m.queue.Recent())
+		}
+		if m.state == ready && m.detail != nil {
+			if key.Matches(msg, drillUp) && m.detail.list.FilterState() == list.Unfiltered {
+				m.detail = nil
+				return m, nil
+			}
+			return m, m.detail.Update(msg)
+		}
 		if m.state == ready {
-			updatedModel, cmd := m.list.Update(msg)
-			if updatedList, ok := updatedModel.(mailList); ok {
-				m.list = updatedList
+			l := m.activeList()
+			if l.list.FilterState() != list.Filtering {
+				switch {
+				case key.Matches(msg, drillUp) && m.drill != nil && l.list.FilterState() == list.Unfiltered:
+					m.drill = nil
+					return m, nil
+				case key.Matches(msg, drillDown):
+					if mail, ok := l.getSelectedMail(); ok {
+						if m.drill == nil && len(mail.Members) > 0 {
+							return m, m.openDrill(NewMembersModel(mail, m.section == stillMailingSection), mail)
+						}
+						return m, m.openDetail(mail)
+					}
+				case key.Matches(msg, showTemplates) && m.drill == nil:
+					if mail, ok := l.getSelectedMail(); ok {
+						if len(mail.Templates) == 0 {
+							return m, m.statusCmd(fmt.Sprintf("Every mail from %s has the same kind of subject", mail.Key))
+						}
+						return m, m.openDrill(NewTemplatesModel(mail, m.section == stillMailingSection), mail)
+					}
+				case key.Matches(msg, showOps):
+					m.panel = &opsPanel{}
+					return m, nil
+				case key.Matches(msg, undoAction):
+					return m, m.undoLast()
+				case key.Matches(msg, switchSection) && m.drill == nil:
+					m.toggleSection()
+					return m, nil
+				case key.Matches(msg, toggleGrouping):
+					m.groupBy = m.groupBy.Next()
+					return m, tea.Batch(m.buildLists(), m.statusCmd(fmt.Sprintf("Grouping mails by %s", m.groupBy)))
+				case key.Matches(msg, nextCategory):
+					m.category = m.category.Next()
+					return m, m.buildLists()
+				case key.Matches(msg, toggleSort):
+					m.sortBy = m.sortBy.Next()
+					return m, tea.Batch(m.buildLists(), m.statusCmd(fmt.Sprintf("Sorting lists by %s", m.sortBy)))
+				case key.Matches(msg, cycleActionAge):
+					m.actionAge = actionAges[(slices.Index(actionAges, m.actionAge)+1)%len(actionAges)]
+					if m.actionAge == 0 {
+						return m, m.statusCmd("Actions apply to every mail")
+					}
+					return m, m.statusCmd(fmt.Sprintf("Actions only apply to mails older than %s", inbox.FormatAge(m.actionAge)))
+				case key.Matches(msg, toggleView):
+					m.view = m.view.Next()
+					return m, m.buildLists()
+				}
 			}
-			cmds = append(cmds, cmd)
+			cmds = append(cmds, m.updateList(l, msg))
 			return m, tea.Batch(cmds...)
 		}
 		if m.state == loading {
@@ -313,16 +513,17 @@ func (m *rootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	default:
+		if m.state == failed {
+			return m, nil
+		}
 		if m.state == loading {
 			_, cmd := m.progress.Update(msg)
 			cmds = append(cmds, cmd)
 			return m, tea.Batch(cmds...)
+		} else if m.detail != nil {
+			return m, m.detail.Update(msg)
 		} else {
-			updatedModel, cmd := m.list.Update(msg)
-			if updatedList, ok := updatedModel.(mailList); ok {
-				m.list = updatedList
-			}
-			cmds = append(cmds, cmd)
+			cmds = append(cmds, m.updateList(m.activeList(), msg))
 			return m, tea.Batch(cmds...)
 		}
 	}
@@ -334,19 +535,319 @@ func (m *rootModel) View() string {
 	if m.state == loading {
 		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.progress.View())
 	}
-	return m.list.View()
+	if m.state == failed {
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.failureView())
+	}
+	if m.action != nil {
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.action.View())
+	}
+	if m.confirm != nil {
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.confirm.View())
+	}
+	if m.summary != nil {
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.summary.View())
+	}
+	if m.panel != nil {
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.panel.View(m.queue.Recent()))
+	}
+	if m.detail != nil {
+		return lipgloss.JoinVertical(lipgloss.Left, m.tabsView(), m.detail.View())
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, m.tabsView(), m.activeList().View())
+}
+
+func (m *rootModel) tabsView() string {
+	tabs := make([]string, 0, len(inbox.Categories)+1)
+	for _, c := range append([]inbox.Category{inbox.AllCategories}, inbox.Categories...) {
+		style := tabStyle
+		if c == m.category {
+			style = activeTabStyle
+		}
+		tabs = append(tabs, style.Render(c.String()))
+	}
+	if n := m.queue.Active(); n > 0 {
+		tabs = append(tabs, warningStyle.Padding(0, 1).Render(fmt.Sprintf("%d operations pending", n)))
+	}
+	return tabBarStyle.Render(lipgloss.JoinHorizontal(lipgloss.Top, tabs...))
+}
+
+// listSize is the room left for lists below the category tabs.
+func (m *rootModel) listSize() tea.WindowSizeMsg {
+	return tea.WindowSizeMsg{
+		Width:  m.width,
+		Height: m.height - lipgloss.Height(m.tabsView()),
+	}
+}
+
+// buildLists groups the mails fetched so far into both sections, so it can
+// run again whenever the grouping changes without scanning the inbox again.
+func (m *rootModel) buildLists() tea.Cmd {
+	rawMailList := slices.DeleteFunc(slices.Clone(inbox.RawMailList(m.mails)), func(rm inbox.RawMail) bool {
+		return m.hidden[rm.ID]
+	})
+	mailingLists := inbox.GetMailingList(rawMailList,
+		inbox.WithMailer(m.svc),
+		inbox.WithGroupKey(m.groupBy),
+		inbox.WithCategory(m.category),
+		inbox.WithSortOrder(m.sortBy),
+		inbox.WithEngagement(m.engagement),
+		inbox.WithViewMode(m.view),
+		inbox.WithOlderThan(m.olderThan),
+		inbox.WithNewerThan(m.newerThan),
+	)
+	if m.journal != nil {
+		m.journal.Annotate(mailingLists)
+	}
+	regular, stillMailing := inbox.SplitStillMailing(mailingLists)
+
+	m.list = NewModel(regular, m.view)
+	m.stillMailing = NewStillMailingModel(stillMailing)
+	m.drill = nil
+	m.detail = nil
+	if len(stillMailing) == 0 {
+		m.section = mailingListsSection
+	}
+
+	if m.width == 0 || m.height == 0 {
+		return nil
+	}
+	size := m.listSize()
+	return tea.Batch(m.updateList(&m.list, size), m.updateList(&m.stillMailing, size))
+}
+
+// measureEngagement samples the read history of every sender shown, which
+// takes a couple of requests each, so it runs once lists are already usable.
+func (m *rootModel) measureEngagement() tea.Cmd {
+	var senders []string
+	for _, l := range []*mailList{&m.list, &m.stillMailing} {
+		for _, item := range l.list.Items() {
+			if mail, ok := item.(inbox.MailingList); ok {
+				senders = append(senders, mail.Senders...)
+			}
+		}
+	}
+
+	return func() tea.Msg {
+		scores, err := m.svc.SenderEngagement(m.ctx, senders, m.engagementWindow)
+		return engagementMeasuredMsg{scores: scores, err: err}
+	}
+}
+
+// applyEngagement annotates the lists in place rather than building them
+// again, so it does not close the view the user is on. Lists ranked by
+// engagement are sorted again now that there is something to rank by.
+func (m *rootModel) applyEngagement() tea.Cmd {
+	var cmds []tea.Cmd
+	lists := []*mailList{&m.list, &m.stillMailing}
+	if m.drill != nil {
+		lists = append(lists, m.drill)
+		m.drillParent = m.drillParent.WithEngagement(m.engagement)
+	}
+	for _, l := range lists {
+		mails := make([]inbox.MailingList, 0, len(l.list.Items()))
+		for _, item := range l.list.Items() {
+			if mail, ok := item.(inbox.MailingList); ok {
+				mails = append(mails, mail.WithEngagement(m.engagement))
+			}
+		}
+		if m.sortBy == inbox.SortByEngagement {
+			inbox.SortMailingLists(mails, m.sortBy)
+		}
+		items := make([]list.Item, 0, len(mails))
+		for _, mail := range mails {
+			items = append(items, mail)
+		}
+		cmds = append(cmds, l.list.SetItems(items))
+	}
+	return tea.Batch(cmds...)
+}
+
+// openDrill shows the break down of a list, into senders or subject
+// templates, on top of the current section.
+func (m *rootModel) openDrill(drill mailList, parent inbox.MailingList) tea.Cmd {
+	m.drill = &drill
+	m.drillParent = parent
+	if m.width == 0 || m.height == 0 {
+		return nil
+	}
+	return m.updateList(m.drill, m.listSize())
+}
+
+// openDetail lists the mails of a list, on top of whatever is shown.
+func (m *rootModel) openDetail(mail inbox.MailingList) tea.Cmd {
+	m.detail = newDetailModel(mail, m.mails, func(id string) (string, error) {
+		return m.svc.GetMessageBody(m.ctx, id)
+	})
+	if m.width != 0 && m.height != 0 {
+		size := m.listSize()
+		m.detail.SetSize(size.Width, size.Height)
+	}
+	return m.detail.loadPreview()
+}
+
+func (m *rootModel) sectionList() *mailList {
+	if m.section == stillMailingSection {
+		return &m.stillMailing
+	}
+	return &m.list
+}
+
+func (m *rootModel) activeList() *mailList {
+	if m.drill != nil {
+		return m.drill
+	}
+	if m.section == stillMailingSection {
+		return &m.stillMailing
+	}
+	return &m.list
+}
+
+func (m *rootModel) toggleSection() {
+	if m.section == stillMailingSection {
+		m.section = mailingListsSection
+		return
+	}
+	if len(m.stillMailing.list.Items()) > 0 {
+		m.section = stillMailingSection
+	}
+}
+
+func (m *rootModel) updateList(l *mailList, msg tea.Msg) tea.Cmd {
+	updatedModel, cmd := l.Update(msg)
+	if updatedList, ok := updatedModel.(mailList); ok {
+		*l = updatedList
+	}
+	return cmd
+}
+
+// removeItem takes the mails just handled out of whichever section still
+// shows their list at idx, as the user may have switched sections while it
+// was being processed. Lists with nothing left are dropped, and senders
+// or templates handled while drilling into a list are taken out of it as well.
+func (m *rootModel) removeItem(mail inbox.MailingList, idx int) {
+	if m.drill != nil && removeAt(m.drill, mail, idx) {
+		parent, left := m.drillParent.Without(mail.UnreadMessagesIDs)
+		m.drillParent = parent
+		if !left {
+			m.drill = nil
+		}
+
+		l := m.sectionList()
+		for i, item := range l.list.Items() {
+			if item, ok := item.(inbox.MailingList); ok && item.Key == parent.Key {
+				if left {
+					l.list.SetItem(i, parent)
+				} else {
+					l.list.RemoveItem(i)
+				}
+				break
+			}
+		}
+		return
+	}
+
+	for _, l := range []*mailList{&m.list, &m.stillMailing} {
+		if removeAt(l, mail, idx) {
+			return
+		}
+	}
+}
+
+func removeAt(l *mailList, mail inbox.MailingList, idx int) bool {
+	items := l.list.Items()
+	if idx >= len(items) {
+		return false
+	}
+	item, ok := items[idx].(inbox.MailingList)
+	if !ok || item.Key != mail.Key {
+		return false
+	}
+	if rest, left := item.Without(mail.UnreadMessagesIDs); left {
+		l.list.SetItem(idx, rest)
+	} else {
+		l.list.RemoveItem(idx)
+	}
+	return true
+}
+
+// removeHandled drops mails wherever their list is shown now, as it may have
+// moved since their operation was queued.
+func (m *rootModel) removeHandled(mail inbox.MailingList) {
+	lists := []*mailList{&m.list, &m.stillMailing}
+	if m.drill != nil {
+		lists = append([]*mailList{m.drill}, lists...)
+	}
+	for _, l := range lists {
+		for i, item := range l.list.Items() {
+			if item, ok := item.(inbox.MailingList); ok && item.Key == mail.Key {
+				m.removeItem(mail, i)
+				return
+			}
+		}
+	}
+}
+
+func (m *rootModel) unmark(key string) {
+	for _, l := range []*mailList{&m.list, &m.stillMailing, m.drill} {
+		if l != nil {
+			l.setMarked(key, false)
+		}
+	}
+}
+
+// actionTarget narrows a list down to the mails old enough for the current
+// age qualifier, and whether any is left.
+func (m *rootModel) actionTarget(mail inbox.MailingList) (inbox.MailingList, bool) {
+	if m.actionAge == 0 {
+		return mail, true
+	}
+	return mail.OlderThan(m.actionAge, time.Now())
+}
+
+// ask shows what op is about to do before queueing it, unless the user chose
+// to skip confirming actions that can be undone.
+func (m *rootModel) ask(op opKind, mails []inbox.MailingList, batch bool) tea.Cmd {
+	if op.reversible() && m.skipReversibleConfirm {
+		return m.proceed(op, mails, batch)
+	}
+
+	var total int
+	var bytes int64
+	for _, mail := range mails {
+		if target, ok := m.actionTarget(mail); ok {
+			total += target.TotalUnreads
+			bytes += target.TotalBytes
+		}
+	}
+	m.action = newActionConfirm(op, mails, batch, total, bytes, m.confirmThreshold)
+	return textinput.Blink
+}
+
+// proceed queues op once confirmed, batches narrowing every list down on
+// their own.
+func (m *rootModel) proceed(op opKind, mails []inbox.MailingList, batch bool) tea.Cmd {
+	if batch {
+		return m.queueBatch(op, mails)
+	}
+	return m.enqueue(tracked{kind: op, mail: mails[0]})
+}
+
+func (m *rootModel) noTargetCmd(mail inbox.MailingList) tea.Cmd {
+	return m.statusCmd(fmt.Sprintf("No mails from %s older than %s", mail.From, inbox.FormatAge(m.actionAge)))
 }
 
 func (m *rootModel) statusCmd(text string) tea.Cmd {
 	return func() tea.Msg {
-		return statusMsg{text: text}
+		return statusMsg{text: inbox.Sanitize(text)}
 	}
 }
 
 func (m *rootModel) handleUnsubscribeError(mail inbox.MailingList, err error) tea.Cmd {
 	var msg string
-	if err == inbox.ErrNoUnsubscriber {
-		msg = fmt.Sprintf("%s has no one-click unsubscribe feature", mail.From)
+	if err == inbox.ErrNoUnsubscriber && len(mail.Senders) > 1 {
+		msg = fmt.Sprintf("%s gathers %d senders, unsubscribe from them one at a time", mail.Key, len(mail.Senders))
+	} else if err == inbox.ErrNoUnsubscriber {
+		msg = fmt.Sprintf("%s has no supported unsubscribe method", mail.From)
 	} else {
 		msg = fmt.Sprintf("Error unsubscribing from %s: %s", mail.From, err)
 	}
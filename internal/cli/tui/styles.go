@@ -14,6 +14,46 @@ var (
 			Foreground(lipgloss.Color("#FFFDF5")).
 			Background(lipgloss.Color("#25A065")).
 			Padding(0, 1)
+
+	tabBarStyle = lipgloss.
+			NewStyle().
+			Padding(1, 2, 0, 2)
+
+	tabStyle = lipgloss.
+			NewStyle().
+			Foreground(lipgloss.Color("#626262")).
+			Padding(0, 1)
+
+	activeTabStyle = lipgloss.
+			NewStyle().
+			Foreground(lipgloss.Color("#FFFDF5")).
+			Background(lipgloss.Color("#25A065")).
+			Padding(0, 1)
+
+	dialogStyle = lipgloss.
+			NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("#25A065")).
+			Padding(1, 2)
+
+	previewStyle = lipgloss.
+			NewStyle().
+			Border(lipgloss.NormalBorder(), false, false, false, true).
+			BorderForeground(lipgloss.Color("#626262")).
+			Padding(0, 1)
+
+	warningStyle = lipgloss.
+			NewStyle().
+			Foreground(lipgloss.Color("#FFA500"))
+
+	markStyle = lipgloss.
+			NewStyle().
+			Foreground(lipgloss.Color("#25A065")).
+			Bold(true)
+
+	dialogHelpStyle = lipgloss.
+			NewStyle().
+			Foreground(lipgloss.Color("#626262"))
 )
 
 const (
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/sverdejot/geemail/internal/undo"
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Undo the latest archive, trash or spam report",
+	Long:  "Bring back the mails of the latest reversible actions, including those taken in earlier sessions",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		count, err := cmd.Flags().GetInt("count")
+		if err != nil {
+			return fmt.Errorf("error reading flag: %w", err)
+		}
+
+		stack, err := openUndo(cmd)
+		if err != nil {
+			return err
+		}
+		if stack.Len() == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "nothing to undo") //nolint:errcheck
+			return nil
+		}
+
+		service, err := newMailService(cmd)
+		if err != nil {
+			return err
+		}
+
+		for range count {
+			e, ok, err := stack.Pop()
+			if err != nil {
+				return fmt.Errorf("unable to update undo stack: %w", err)
+			}
+			if !ok {
+				fmt.Fprintln(cmd.OutOrStdout(), "nothing left to undo") //nolint:errcheck
+				return nil
+			}
+			if err := service.Revert(cmd.Context(), e.LabelChanges()); err != nil {
+				if perr := stack.Push(e); perr != nil {
+					return fmt.Errorf("unable to undo %s of %s, and it was lost from the undo stack: %w", e.Action, e.Target, err)
+				}
+				return fmt.Errorf("unable to undo %s of %s: %w", e.Action, e.Target, err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "undid %s of %d mails from %s, done %s\n", e.Action, len(e.IDs()), e.Target, e.At.Format("2 Jan 2006 15:04")) //nolint:errcheck
+		}
+		return nil
+	},
+}
+
+// openUndo loads the undo stack of the mailbox being cleaned up.
+func openUndo(cmd *cobra.Command) (*undo.Stack, error) {
+	subject, err := cmd.Flags().GetString("subject")
+	if err != nil {
+		return nil, fmt.Errorf("error reading flag: %w", err)
+	}
+	return openState(cmd, func() (*undo.Stack, error) {
+		return undo.Open(subject)
+	}, "undo stack", "starting with nothing to undo")
+}
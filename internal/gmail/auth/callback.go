@@ -2,14 +2,14 @@ package auth
 
 import (
 	_ "embed"
-	"log"
+	"fmt"
 	"net/http"
 )
 
 //go:embed static/callback.html
 var callbackPage []byte
 
-func callback() string {
+func callback() (string, error) {
 	var code string
 
 	srv := &http.Server{
@@ -32,8 +32,8 @@ func callback() string {
 	srv.Handler = mux
 
 	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("failed listening for callback requests: %v", err)
+		return "", fmt.Errorf("failed listening for callback requests: %w", err)
 	}
 
-	return code
+	return code, nil
 }
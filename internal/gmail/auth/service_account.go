@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/gmail/v1"
+)
+
+// only the full mailbox scope is requested: every scope must be granted to the
+// service account client ID in the Workspace admin console, so keep it short
+var serviceAccountScopes = []string{
+	gmail.MailGoogleComScope,
+}
+
+const serviceAccountEnvKey = "GEEMAIL_SERVICE_ACCOUNT_CREDENTIALS"
+
+// NewServiceAccountClient returns a client acting on behalf of subject through
+// domain-wide delegation, so no interactive OAuth consent is needed.
+func NewServiceAccountClient(ctx context.Context, subject string) (*http.Client, error) {
+	if subject == "" {
+		return nil, fmt.Errorf("no subject to impersonate")
+	}
+	key := os.Getenv(serviceAccountEnvKey)
+	if key == "" {
+		return nil, fmt.Errorf("no service account credentials found for geemail, set %s", serviceAccountEnvKey)
+	}
+	config, err := google.JWTConfigFromJSON([]byte(key), serviceAccountScopes...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read service account key: %w", err)
+	}
+	config.Subject = subject
+	return config.Client(ctx), nil
+}
@@ -3,14 +3,13 @@ package auth
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
-	"os/exec"
 	"path"
-	"runtime"
 
+	"github.com/sverdejot/geemail/internal/browser"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/gmail/v1"
@@ -31,6 +30,31 @@ const (
 )
 
 func NewHTTPClient(ctx context.Context) (*http.Client, error) {
+	config, err := oauthConfig()
+	if err != nil {
+		return nil, err
+	}
+	tok, err := tokenFromEnv()
+	if err != nil {
+		return signIn(ctx, config)
+	}
+	return config.Client(context.Background(), tok), nil
+}
+
+// Reauthenticate drops the saved token and goes through the browser consent
+// again, for when the token expired or was revoked.
+func Reauthenticate(ctx context.Context) (*http.Client, error) {
+	config, err := oauthConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := removeToken(); err != nil {
+		return nil, err
+	}
+	return signIn(ctx, config)
+}
+
+func oauthConfig() (*oauth2.Config, error) {
 	credentials := os.Getenv(credentialsEnvKey)
 	if credentials == "" {
 		return nil, fmt.Errorf("no credentials found for geemail")
@@ -39,30 +63,37 @@ func NewHTTPClient(ctx context.Context) (*http.Client, error) {
 	if err != nil {
 		return nil, fmt.Errorf("cannot read config file: %w", err)
 	}
-	tok, err := tokenFromEnv()
+	return config, nil
+}
+
+func signIn(ctx context.Context, config *oauth2.Config) (*http.Client, error) {
+	tok, err := getTokenFromWeb(ctx, config)
 	if err != nil {
-		tok = getTokenFromWeb(ctx, config)
-		if err := saveToken(tok); err != nil {
-			log.Fatalf("error saving token: %v\n", err)
-		}
+		return nil, err
+	}
+	if err := saveToken(tok); err != nil {
+		return nil, fmt.Errorf("error saving token: %w", err)
 	}
 	return config.Client(context.Background(), tok), nil
 }
 
-func getTokenFromWeb(ctx context.Context, config *oauth2.Config) *oauth2.Token {
+func getTokenFromWeb(ctx context.Context, config *oauth2.Config) (*oauth2.Token, error) {
 	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
 
-	if err := open(authURL); err != nil {
-		log.Fatalf("cannot open URL to redeem token: %v", err)
+	if err := browser.Open(authURL); err != nil {
+		return nil, fmt.Errorf("cannot open URL to redeem token: %w", err)
 	}
 
-	authCode := callback()
+	authCode, err := callback()
+	if err != nil {
+		return nil, err
+	}
 	tok, err := config.Exchange(ctx, authCode)
 	if err != nil {
-		log.Fatalf("unable to retrieve token from web: %v", err)
+		return nil, fmt.Errorf("unable to retrieve token from web: %w", err)
 	}
 
-	return tok
+	return tok, nil
 }
 
 func tokenFromEnv() (*oauth2.Token, error) {
@@ -83,6 +114,18 @@ func tokenFromEnv() (*oauth2.Token, error) {
 	return &token, nil
 }
 
+func removeToken() error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("cannot get user home dir: %w", err)
+	}
+	err = os.Remove(path.Join(home, tokenFileDir, tokenFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("cannot remove token: %w", err)
+	}
+	return nil
+}
+
 func saveToken(token *oauth2.Token) error {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -96,20 +139,3 @@ func saveToken(token *oauth2.Token) error {
 	defer f.Close() //nolint:errcheck
 	return json.NewEncoder(f).Encode(token)
 }
-
-func open(url string) error {
-	var cmd string
-	var args []string
-
-	switch runtime.GOOS {
-	case "windows":
-		cmd = "cmd"
-		args = []string{"/c", "start"}
-	case "darwin":
-		cmd = "open"
-	default:
-		cmd = "xdg-open"
-	}
-	args = append(args, url)
-	return exec.Command(cmd, args...).Start()
-}
@@ -0,0 +1,140 @@
+package gmail
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/text/encoding/htmlindex"
+	"google.golang.org/api/gmail/v1"
+)
+
+var blankLinesRe = regexp.MustCompile(`\n{3,}`)
+
+// GetMessageBody fetches a whole message and returns its text, preferring the
+// text/plain part and converting the HTML one otherwise.
+func (s *MailService) GetMessageBody(ctx context.Context, id string) (string, error) {
+	if err := s.lim.WaitN(ctx, messagesGetQuotaUsage); err != nil {
+		return "", err
+	}
+
+	msg, err := s.service().Users.Messages.
+		Get(s.user, id).
+		Format("full").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return "", fmt.Errorf("cannot get message %s: %w", id, err)
+	}
+
+	if part := findPart(msg.Payload, "text/plain"); part != nil {
+		return decodePart(part)
+	}
+	if part := findPart(msg.Payload, "text/html"); part != nil {
+		body, err := decodePart(part)
+		if err != nil {
+			return "", err
+		}
+		return htmlToText(body), nil
+	}
+	return "", fmt.Errorf("message %s has no text body", id)
+}
+
+// findPart returns the first part of the given type holding some data, depth
+// first, skipping attachments.
+func findPart(part *gmail.MessagePart, mimeType string) *gmail.MessagePart {
+	if part == nil {
+		return nil
+	}
+	if part.Filename == "" && strings.EqualFold(part.MimeType, mimeType) && part.Body != nil && part.Body.Data != "" {
+		return part
+	}
+	for _, p := range part.Parts {
+		if found := findPart(p, mimeType); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// decodePart undoes the base64url encoding of the API and converts the text
+// to UTF-8 from whatever charset it declares.
+func decodePart(part *gmail.MessagePart) (string, error) {
+	data, err := base64.URLEncoding.DecodeString(part.Body.Data)
+	if err != nil {
+		data, err = base64.RawURLEncoding.DecodeString(part.Body.Data)
+		if err != nil {
+			return "", fmt.Errorf("cannot decode body: %w", err)
+		}
+	}
+
+	for _, h := range part.Headers {
+		if !strings.EqualFold(h.Name, "Content-Type") {
+			continue
+		}
+		_, params, err := mime.ParseMediaType(h.Value)
+		if err != nil {
+			break
+		}
+		charset := params["charset"]
+		if charset == "" || strings.EqualFold(charset, "utf-8") {
+			break
+		}
+		enc, err := htmlindex.Get(charset)
+		if err != nil {
+			break
+		}
+		if decoded, err := io.ReadAll(enc.NewDecoder().Reader(bytes.NewReader(data))); err == nil {
+			data = decoded
+		}
+		break
+	}
+	return string(data), nil
+}
+
+// htmlToText keeps the readable text of an HTML body, breaking lines at block
+// elements and leaving out scripts, styles and the head.
+func htmlToText(body string) string {
+	var sb strings.Builder
+	z := html.NewTokenizer(strings.NewReader(body))
+	skip := 0
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			text := blankLinesRe.ReplaceAllString(sb.String(), "\n\n")
+			return strings.TrimSpace(text)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "script", "style", "head", "title":
+				skip++
+			case "br", "p", "div", "tr", "table", "h1", "h2", "h3", "h4", "h5", "h6":
+				sb.WriteString("\n")
+			case "li":
+				sb.WriteString("\n• ")
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "script", "style", "head", "title":
+				skip = max(skip-1, 0)
+			case "p", "div", "tr", "table", "h1", "h2", "h3", "h4", "h5", "h6":
+				sb.WriteString("\n")
+			}
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			if text := strings.Join(strings.Fields(string(z.Text())), " "); text != "" {
+				sb.WriteString(text)
+				sb.WriteString(" ")
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package gmail
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sverdejot/geemail/internal/inbox"
+)
+
+// counts are sampled from a single page, exact up to this many mails and
+// estimated by Gmail past it
+const engagementSampleSize = 500
+
+// EngagementError reports senders whose engagement could not be measured,
+// the others being measured anyway.
+type EngagementError struct {
+	Failed, Total int
+	// the error of the last sender that failed
+	Err error
+}
+
+func (e *EngagementError) Error() string {
+	return fmt.Sprintf("cannot measure engagement of %d out of %d senders: %s", e.Failed, e.Total, e.Err)
+}
+
+func (e *EngagementError) Unwrap() error {
+	return e.Err
+}
+
+// SenderEngagement counts read and unread mails received from every sender
+// within the window, in all mail and not only the inbox. Senders failing to
+// be measured are left out and reported with an EngagementError, along with
+// the scores of the rest. Senders that are not an address, such as the one
+// standing for mails without a usable From, are skipped.
+func (s *MailService) SenderEngagement(ctx context.Context, senders []string, window time.Duration) (map[string]inbox.Engagement, error) {
+	newerThan := fmt.Sprintf("newer_than:%dd", max(int(window.Hours()/24), 1))
+	senders = slices.DeleteFunc(slices.Clone(senders), func(sender string) bool {
+		_, ok := fromQuery(sender)
+		return !ok
+	})
+
+	var mu sync.Mutex
+	scores := make(map[string]inbox.Engagement, len(senders))
+	partial := &EngagementError{Total: len(senders)}
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		partial.Failed++
+		partial.Err = err
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(poolSize)
+	jobs := make(chan string, poolSize)
+
+	for range poolSize {
+		go func() {
+			defer wg.Done()
+			for sender := range jobs {
+				q, _ := fromQuery(sender)
+				from := q + " " + newerThan
+				read, err := s.countMessages(ctx, from+" is:read")
+				if err != nil {
+					fail(err)
+					continue
+				}
+				unread, err := s.countMessages(ctx, from+" is:unread")
+				if err != nil {
+					fail(err)
+					continue
+				}
+				mu.Lock()
+				scores[sender] = inbox.Engagement{Read: read, Unread: unread}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, sender := range senders {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- sender
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("cannot measure engagement: %w", err)
+	}
+	if partial.Failed > 0 {
+		return scores, partial
+	}
+	return scores, nil
+}
+
+// fromQuery matches the mails sent by sender in Gmail search syntax, provided
+// it is a plain address.
+func fromQuery(sender string) (string, bool) {
+	addr, err := mail.ParseAddress(sender)
+	if err != nil || addr.Name != "" || !strings.EqualFold(addr.Address, sender) {
+		return "", false
+	}
+	// anything Gmail would read as an operator makes the address unusable
+	if strings.ContainsAny(sender, "() \"{}") {
+		return "", false
+	}
+	return "from:(" + sender + ")", true
+}
+
+func (s *MailService) countMessages(ctx context.Context, q string) (int64, error) {
+	if err := s.lim.WaitN(ctx, messagesListQuotaUsage); err != nil {
+		return 0, err
+	}
+	resp, err := s.service().Users.Messages.
+		List(s.user).
+		Q(q).
+		MaxResults(engagementSampleSize).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return 0, fmt.Errorf("cannot count messages matching %q: %w", q, err)
+	}
+	if resp.NextPageToken == "" {
+		return int64(len(resp.Messages)), nil
+	}
+	return max(resp.ResultSizeEstimate, int64(len(resp.Messages))), nil
+}
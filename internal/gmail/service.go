@@ -1,11 +1,16 @@
 package gmail
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"fmt"
 	"log"
+	"mime"
 	"net/http"
 	"runtime"
+	"slices"
+	"strings"
 	"sync"
 
 	"github.com/sverdejot/geemail/internal/inbox"
@@ -19,33 +24,125 @@ var (
 )
 
 const (
-	user = "me"
+	// the authenticated user, overridden when impersonating through a
+	// service account
+	defaultUser = "me"
 
 	// non-classified emails nor reads ones, reasoning is that read messages
 	// may be interesting for the user
 	query      = "is:unread has:nouserlabels"
 	inboxLabel = "INBOX"
-    thrashLabel = "THRASH"
+	trashLabel = "TRASH"
+	spamLabel  = "SPAM"
 
 	// max query results, set to the API maximum, default is 100
 	maxResults = 500
 
+	// most IDs batchDelete and batchModify accept in a single call
+	maxBatchIDs = 1000
+
 	// usage limit is 15_000 u/min per user
 	apiQuotaUsagePerSec = 15_000 / 60
 
 	// quota consumption per operation
-	messagesListQuotaUsage = 5
-	messagesGetQuotaUsage  = 5
-    batchDeleteQuotaUsage  = 50 
-    batchModifyQuotausage  = 50
+	messagesListQuotaUsage  = 5
+	messagesGetQuotaUsage   = 5
+	batchDeleteQuotaUsage   = 50
+	batchModifyQuotausage   = 50
+	messagesSendQuotaUsage  = 100
+	filtersCreateQuotaUsage = 5
+)
+
+// LabelDiff is what an action changes on the labels of every mail it touches,
+// enough to undo it later.
+type LabelDiff struct {
+	Added   []string
+	Removed []string
+}
+
+// LabelChange is what an action actually changed on some mails, which may be
+// less than its LabelDiff for mails that had some of the labels already.
+type LabelChange struct {
+	IDs []string
+	LabelDiff
+}
+
+var (
+	ArchiveDiff = LabelDiff{Removed: []string{inboxLabel}}
+	TrashDiff   = LabelDiff{Added: []string{trashLabel}, Removed: []string{inboxLabel}}
+	SpamDiff    = LabelDiff{Added: []string{spamLabel}, Removed: []string{inboxLabel}}
 )
 
+// Reverse swaps the labels added and removed, turning an action into its undo.
+func (d LabelDiff) Reverse() LabelDiff {
+	return LabelDiff{Added: d.Removed, Removed: d.Added}
+}
+
+// On narrows the diff down to what it changes on a mail carrying labels.
+func (d LabelDiff) On(labels []string) LabelDiff {
+	var on LabelDiff
+	for _, l := range d.Added {
+		if !slices.Contains(labels, l) {
+			on.Added = append(on.Added, l)
+		}
+	}
+	for _, l := range d.Removed {
+		if slices.Contains(labels, l) {
+			on.Removed = append(on.Removed, l)
+		}
+	}
+	return on
+}
+
+func (d LabelDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+// Changes groups msgs by what diff changes on each of them, as of the labels
+// they had when scanned, keeping the order they first appear in.
+func Changes(msgs []inbox.MessageRef, diff LabelDiff) []LabelChange {
+	var changes []LabelChange
+	for _, msg := range msgs {
+		on := diff.On(msg.Labels)
+		i := slices.IndexFunc(changes, func(c LabelChange) bool {
+			return slices.Equal(c.Added, on.Added) && slices.Equal(c.Removed, on.Removed)
+		})
+		if i < 0 {
+			changes = append(changes, LabelChange{LabelDiff: on})
+			i = len(changes) - 1
+		}
+		changes[i].IDs = append(changes[i].IDs, msg.ID)
+	}
+	return changes
+}
+
 type MailService struct {
-	srv *gmail.Service
-	lim *rate.Limiter
+	// swapped by UseClient while requests may be in flight, read it through
+	// service
+	srvMu sync.RWMutex
+	srv   *gmail.Service
+	lim   *rate.Limiter
+	user  string
+
+	// IDs of the labels marking mails trashed by geemail by name, resolved
+	// on first use
+	markerMu  sync.Mutex
+	markerIDs map[string]string
+}
+
+type MailServiceOpt func(*MailService)
+
+// WithUser makes every request target the given mailbox instead of the
+// authenticated one.
+func WithUser(user string) MailServiceOpt {
+	return func(s *MailService) {
+		if user != "" {
+			s.user = user
+		}
+	}
 }
 
-func NewMessageService(ctx context.Context, client *http.Client) (*MailService, error) {
+func NewMessageService(ctx context.Context, client *http.Client, opts ...MailServiceOpt) (*MailService, error) {
 	srv, err := gmail.NewService(ctx, option.WithHTTPClient(client))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create gmail service: %w", err)
@@ -55,10 +152,36 @@ func NewMessageService(ctx context.Context, client *http.Client) (*MailService,
 		rate.Limit(apiQuotaUsagePerSec), apiQuotaUsagePerSec,
 	)
 
-	return &MailService{
-		srv: srv,
-		lim: lim,
-	}, nil
+	s := &MailService{
+		srv:       srv,
+		lim:       lim,
+		user:      defaultUser,
+		markerIDs: make(map[string]string),
+	}
+	for _, fn := range opts {
+		fn(s)
+	}
+	return s, nil
+}
+
+// UseClient swaps the HTTP client requests go through, such as after signing
+// in again. Requests already sent finish on the previous one.
+func (s *MailService) UseClient(ctx context.Context, client *http.Client) error {
+	srv, err := gmail.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return fmt.Errorf("failed to create gmail service: %w", err)
+	}
+	s.srvMu.Lock()
+	defer s.srvMu.Unlock()
+	s.srv = srv
+	return nil
+}
+
+// service returns the Gmail client requests currently go through.
+func (s *MailService) service() *gmail.Service {
+	s.srvMu.RLock()
+	defer s.srvMu.RUnlock()
+	return s.srv
 }
 
 func (s *MailService) StreamUnreadMessages(ctx context.Context) (chan inbox.RawMail, error) {
@@ -68,9 +191,14 @@ func (s *MailService) StreamUnreadMessages(ctx context.Context) (chan inbox.RawM
 
 	ids, err := s.GetUnreadMessageIDs(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch messages for user %s: %w", user, err)
+		return nil, fmt.Errorf("failed to fetch messages for user %s: %w", s.user, err)
 	}
+	return s.streamMessages(ctx, ids), nil
+}
 
+// streamMessages fetches the given messages through the worker pool, those
+// failing after retries being left out.
+func (s *MailService) streamMessages(ctx context.Context, ids []string) chan inbox.RawMail {
 	var wg sync.WaitGroup
 	wg.Add(poolSize)
 	jobs := make(chan string, poolSize)
@@ -92,7 +220,7 @@ func (s *MailService) StreamUnreadMessages(ctx context.Context) (chan inbox.RawM
 		close(results)
 	}()
 
-	return results, nil
+	return results
 }
 
 func (s *MailService) GetUnreadMessages(ctx context.Context) ([]inbox.RawMail, error) {
@@ -128,8 +256,8 @@ func (s *MailService) getMessageWorker(
 				continue
 			}
 
-			msg, err := s.srv.Users.Messages.
-				Get(user, msgID).
+			msg, err := s.service().Users.Messages.
+				Get(s.user, msgID).
 				Context(ctx).
 				Do()
 
@@ -140,11 +268,17 @@ func (s *MailService) getMessageWorker(
 					inbox.WithSnippet(msg),
 					inbox.WithSubject(msg),
 					inbox.WithHeaders(msg),
+					inbox.WithAuthor(),
+					inbox.WithDate(msg),
+					inbox.WithRecipients(msg),
+					inbox.WithLabels(msg),
+					inbox.WithSize(msg),
+					inbox.WithAttachments(msg),
 				)
 				if err != nil {
-					// something about the mail cannot be parsed, continue
-					// without retrying
-					continue
+					// fetching it again would not make it any less broken
+					lastErr = fmt.Errorf("unparseable message: %w", err)
+					break
 				}
 				results <- mail
 				lastErr = nil
@@ -161,8 +295,8 @@ func (s *MailService) getMessageWorker(
 }
 
 func (s *MailService) GetTotalUnreads(ctx context.Context) (int64, error) {
-	req := s.srv.Users.Labels.
-		Get(user, inboxLabel).
+	req := s.service().Users.Labels.
+		Get(s.user, inboxLabel).
 		Context(ctx)
 
 	label, err := req.Do()
@@ -181,13 +315,17 @@ func (s *MailService) GetUnreadMessageIDs(ctx context.Context) ([]string, error)
 		return nil, fmt.Errorf("cannot fetch total mail count: %w", err)
 	}
 
-	req := s.srv.Users.Messages.
-		List(user).
+	req := s.service().Users.Messages.
+		List(s.user).
 		Q(query).
 		MaxResults(maxResults).
 		Context(ctx)
 
+	// pages failing this many times in a row are not transient
+	const maxRetries = 3
+
 	mailIDs := make([]string, 0, totalMailCount)
+	var failures int
 	for currentMailCount < totalMailCount {
 		if err := s.lim.WaitN(ctx, messagesListQuotaUsage); err != nil {
 			// probably a ctx.Canceled, so better to return i.o. continue
@@ -197,8 +335,12 @@ func (s *MailService) GetUnreadMessageIDs(ctx context.Context) ([]string, error)
 			PageToken(pageToken).
 			Do()
 		if err != nil {
+			if failures++; failures == maxRetries {
+				return nil, fmt.Errorf("cannot fetch whole mailing list: %w", err)
+			}
 			continue
 		}
+		failures = 0
 		pageToken = resp.NextPageToken
 		currentMailCount += int64(len(resp.Messages))
 
@@ -209,42 +351,161 @@ func (s *MailService) GetUnreadMessageIDs(ctx context.Context) ([]string, error)
 }
 
 func (s *MailService) BulkDelete(ctx context.Context, ids []string) error {
-    if err := s.lim.WaitN(ctx, batchDeleteQuotaUsage); err != nil {
-        return fmt.Errorf("error bulk-deleting %d mails: %w", len(ids), err)
-    }
-	req := s.srv.Users.Messages.
-		BatchDelete(user, &gmail.BatchDeleteMessagesRequest{Ids: ids}).
-		Context(ctx)
-
-	return req.Do()
+	for chunk := range slices.Chunk(ids, maxBatchIDs) {
+		if err := s.lim.WaitN(ctx, batchDeleteQuotaUsage); err != nil {
+			return fmt.Errorf("error bulk-deleting %d mails: %w", len(ids), err)
+		}
+		err := s.service().Users.Messages.
+			BatchDelete(s.user, &gmail.BatchDeleteMessagesRequest{Ids: chunk}).
+			Context(ctx).
+			Do()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *MailService) BulkArchive(ctx context.Context, ids []string) error {
-    if err := s.lim.WaitN(ctx, batchModifyQuotausage); err != nil {
-        return fmt.Errorf("error archiving %d mails: %w", len(ids), err)
-    }
-	req := s.srv.Users.Messages.
-		BatchModify(user, &gmail.BatchModifyMessagesRequest{
-			Ids:            ids,
-			RemoveLabelIds: []string{inboxLabel},
-		}).
-		Context(ctx)
+	if err := s.BulkModify(ctx, ids, ArchiveDiff); err != nil {
+		return fmt.Errorf("error archiving %d mails: %w", len(ids), err)
+	}
+	return nil
+}
+
+// BulkTrash moves the given mails to the trash, marking them so a later run
+// can find and restore them, to the inbox only for those taken out of it.
+func (s *MailService) BulkTrash(ctx context.Context, msgs []inbox.MessageRef) error {
+	marker, err := s.markerLabelID(ctx, trashedMarkerLabel)
+	if err != nil {
+		return fmt.Errorf("error moving %d mails to trash: %w", len(msgs), err)
+	}
+	inboxMarker, err := s.markerLabelID(ctx, trashedFromInboxLabel)
+	if err != nil {
+		return fmt.Errorf("error moving %d mails to trash: %w", len(msgs), err)
+	}
+	for _, c := range Changes(msgs, TrashDiff) {
+		diff := LabelDiff{
+			Added:   append(slices.Clone(TrashDiff.Added), marker),
+			Removed: TrashDiff.Removed,
+		}
+		if slices.Contains(c.Removed, inboxLabel) {
+			diff.Added = append(diff.Added, inboxMarker)
+		}
+		if err := s.BulkModify(ctx, c.IDs, diff); err != nil {
+			return fmt.Errorf("error moving %d mails to trash: %w", len(msgs), err)
+		}
+	}
+	return nil
+}
+
+// ReportSpam moves the given mails to spam, which also teaches Gmail's
+// classifier about the sender.
+func (s *MailService) ReportSpam(ctx context.Context, ids []string) error {
+	if err := s.BulkModify(ctx, ids, SpamDiff); err != nil {
+		return fmt.Errorf("error reporting %d mails as spam: %w", len(ids), err)
+	}
+	return nil
+}
+
+// BulkModify applies diff to the labels of every given mail, in as many
+// calls as the API limit on IDs requires.
+func (s *MailService) BulkModify(ctx context.Context, ids []string, diff LabelDiff) error {
+	for chunk := range slices.Chunk(ids, maxBatchIDs) {
+		if err := s.lim.WaitN(ctx, batchModifyQuotausage); err != nil {
+			return err
+		}
+		err := s.service().Users.Messages.
+			BatchModify(s.user, &gmail.BatchModifyMessagesRequest{
+				Ids:            chunk,
+				AddLabelIds:    diff.Added,
+				RemoveLabelIds: diff.Removed,
+			}).
+			Context(ctx).
+			Do()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	return req.Do()
+// Revert undoes what an action changed on each mail, bringing them back
+// where they were. Mails leaving the trash lose the geemail markers as well.
+func (s *MailService) Revert(ctx context.Context, changes []LabelChange) error {
+	for _, c := range changes {
+		reverse := c.Reverse()
+		if slices.Contains(c.Added, trashLabel) {
+			for _, name := range []string{trashedMarkerLabel, trashedFromInboxLabel} {
+				marker, err := s.existingMarkerLabelID(ctx, name)
+				if err != nil {
+					return fmt.Errorf("error restoring %d mails: %w", len(c.IDs), err)
+				}
+				if marker != "" {
+					reverse.Removed = append(slices.Clone(reverse.Removed), marker)
+				}
+			}
+		}
+		if reverse.empty() {
+			continue
+		}
+		if err := s.BulkModify(ctx, c.IDs, reverse); err != nil {
+			return fmt.Errorf("error restoring %d mails: %w", len(c.IDs), err)
+		}
+	}
+	return nil
 }
 
-func (s *MailService) BulkTrash(ctx context.Context, ids []string) error {
-    if err := s.lim.WaitN(ctx, batchModifyQuotausage); err != nil {
-        return fmt.Errorf("error moving %d mails to thrash: %w", len(ids), err)
-    }
-	req := s.srv.Users.Messages.
-		BatchModify(user, &gmail.BatchModifyMessagesRequest{
-			Ids:         ids,
-			AddLabelIds: []string{thrashLabel},
+// CreateTrashFilter makes Gmail trash every future mail from a list, matched
+// by its List-Id when known and by sender otherwise.
+func (s *MailService) CreateTrashFilter(ctx context.Context, from, listID string) error {
+	if err := s.lim.WaitN(ctx, filtersCreateQuotaUsage); err != nil {
+		return fmt.Errorf("error creating filter for %s: %w", from, err)
+	}
+
+	criteria := &gmail.FilterCriteria{From: from}
+	if listID != "" {
+		criteria = &gmail.FilterCriteria{Query: fmt.Sprintf("list:%s", listID)}
+	}
+
+	_, err := s.service().Users.Settings.Filters.
+		Create(s.user, &gmail.Filter{
+			Criteria: criteria,
+			Action: &gmail.FilterAction{
+				AddLabelIds:    []string{trashLabel},
+				RemoveLabelIds: []string{inboxLabel},
+			},
 		}).
-		Context(ctx)
+		Context(ctx).
+		Do()
+	if err != nil {
+		return fmt.Errorf("error creating filter for %s: %w", from, err)
+	}
+	return nil
+}
+
+// SendMail sends a plain text message from the service user, returning the ID
+// of the sent message.
+func (s *MailService) SendMail(ctx context.Context, to []string, subject, body string) (string, error) {
+	if err := s.lim.WaitN(ctx, messagesSendQuotaUsage); err != nil {
+		return "", fmt.Errorf("error sending mail to %s: %w", strings.Join(to, ", "), err)
+	}
 
-	return req.Do()
+	var raw bytes.Buffer
+	fmt.Fprintf(&raw, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&raw, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	raw.WriteString("MIME-Version: 1.0\r\n")
+	raw.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+	raw.WriteString(body)
+
+	msg, err := s.service().Users.Messages.
+		Send(s.user, &gmail.Message{Raw: base64.URLEncoding.EncodeToString(raw.Bytes())}).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return "", fmt.Errorf("error sending mail to %s: %w", strings.Join(to, ", "), err)
+	}
+	return msg.Id, nil
 }
 
 func getIds(msgs []*gmail.Message) []string {
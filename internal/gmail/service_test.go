@@ -0,0 +1,70 @@
+package gmail
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sverdejot/geemail/internal/inbox"
+)
+
+func TestChanges(t *testing.T) {
+	msgs := []inbox.MessageRef{
+		{ID: "inbox-1", Labels: []string{"UNREAD", inboxLabel}},
+		{ID: "archived", Labels: []string{"UNREAD"}},
+		{ID: "inbox-2", Labels: []string{inboxLabel, "CATEGORY_UPDATES"}},
+	}
+
+	tests := []struct {
+		name string
+		diff LabelDiff
+		want []LabelChange
+	}{
+		{
+			name: "archive",
+			diff: ArchiveDiff,
+			want: []LabelChange{
+				{IDs: []string{"inbox-1", "inbox-2"}, LabelDiff: LabelDiff{Removed: []string{inboxLabel}}},
+				{IDs: []string{"archived"}},
+			},
+		},
+		{
+			name: "trash",
+			diff: TrashDiff,
+			want: []LabelChange{
+				{IDs: []string{"inbox-1", "inbox-2"}, LabelDiff: TrashDiff},
+				{IDs: []string{"archived"}, LabelDiff: LabelDiff{Added: []string{trashLabel}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Changes(msgs, tt.diff); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Changes() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromQuery(t *testing.T) {
+	tests := []struct {
+		sender string
+		want   string
+		ok     bool
+	}{
+		{"news@example.com", "from:(news@example.com)", true},
+		{"first.last+tag@mail.example.co.uk", "from:(first.last+tag@mail.example.co.uk)", true},
+		{"(unknown sender)", "", false},
+		{"", "", false},
+		{"not an address", "", false},
+		{`"quoted local"@example.com`, "", false},
+		{"News <news@example.com>", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sender, func(t *testing.T) {
+			got, ok := fromQuery(tt.sender)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("fromQuery(%q) = %q, %v, want %q, %v", tt.sender, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
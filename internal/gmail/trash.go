@@ -0,0 +1,157 @@
+package gmail
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/sverdejot/geemail/internal/inbox"
+	"google.golang.org/api/gmail/v1"
+)
+
+const (
+	// label marking the mails geemail trashed, hidden from Gmail's label list
+	trashedMarkerLabel = "geemail/trashed"
+	// label marking, among them, those taken out of the inbox, the only ones
+	// to go back there when restored
+	trashedFromInboxLabel = "geemail/trashed-from-inbox"
+
+	labelsListQuotaUsage   = 1
+	labelsCreateQuotaUsage = 5
+)
+
+// markerLabelID returns the ID of a label marking trashed mails, creating
+// the label the first time geemail needs it.
+func (s *MailService) markerLabelID(ctx context.Context, name string) (string, error) {
+	s.markerMu.Lock()
+	defer s.markerMu.Unlock()
+
+	if err := s.findMarkerLabels(ctx, name); err != nil || s.markerIDs[name] != "" {
+		return s.markerIDs[name], err
+	}
+
+	if err := s.lim.WaitN(ctx, labelsCreateQuotaUsage); err != nil {
+		return "", err
+	}
+	l, err := s.service().Users.Labels.
+		Create(s.user, &gmail.Label{
+			Name:                  name,
+			LabelListVisibility:   "labelHide",
+			MessageListVisibility: "hide",
+		}).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return "", fmt.Errorf("cannot create label %s: %w", name, err)
+	}
+	s.markerIDs[name] = l.Id
+	return l.Id, nil
+}
+
+// existingMarkerLabelID returns the ID of a label marking trashed mails, or
+// an empty one if geemail never needed it in this mailbox.
+func (s *MailService) existingMarkerLabelID(ctx context.Context, name string) (string, error) {
+	s.markerMu.Lock()
+	defer s.markerMu.Unlock()
+
+	err := s.findMarkerLabels(ctx, name)
+	return s.markerIDs[name], err
+}
+
+// findMarkerLabels looks the labels marking trashed mails up unless name was
+// already found, leaving those that do not exist out of markerIDs. Callers
+// must hold markerMu.
+func (s *MailService) findMarkerLabels(ctx context.Context, name string) error {
+	if s.markerIDs[name] != "" {
+		return nil
+	}
+
+	if err := s.lim.WaitN(ctx, labelsListQuotaUsage); err != nil {
+		return err
+	}
+	labels, err := s.service().Users.Labels.List(s.user).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("cannot list labels: %w", err)
+	}
+	for _, l := range labels.Labels {
+		if l.Name == trashedMarkerLabel || l.Name == trashedFromInboxLabel {
+			s.markerIDs[l.Name] = l.Id
+		}
+	}
+	return nil
+}
+
+// GetTrashedMessages returns the mails geemail trashed that are still in the
+// trash, from this run or earlier ones. It leaves the mailbox untouched, so
+// none are found if geemail never trashed anything.
+func (s *MailService) GetTrashedMessages(ctx context.Context) ([]inbox.RawMail, error) {
+	marker, err := s.existingMarkerLabelID(ctx, trashedMarkerLabel)
+	if err != nil || marker == "" {
+		return nil, err
+	}
+
+	ids, err := s.trashedIDs(ctx, marker)
+	if err != nil {
+		return nil, err
+	}
+
+	mails := make([]inbox.RawMail, 0, len(ids))
+	for mail := range s.streamMessages(ctx, ids) {
+		mails = append(mails, mail)
+	}
+	return mails, nil
+}
+
+// RestoreTrashed takes the given mails out of the trash, back to the inbox
+// for those geemail took out of it.
+func (s *MailService) RestoreTrashed(ctx context.Context, ids []string) error {
+	inboxMarker, err := s.existingMarkerLabelID(ctx, trashedFromInboxLabel)
+	if err != nil {
+		return fmt.Errorf("error restoring %d mails: %w", len(ids), err)
+	}
+	var fromInbox []string
+	if inboxMarker != "" {
+		if fromInbox, err = s.trashedIDs(ctx, inboxMarker); err != nil {
+			return fmt.Errorf("error restoring %d mails: %w", len(ids), err)
+		}
+	}
+
+	toInbox := LabelChange{LabelDiff: TrashDiff}
+	elsewhere := LabelChange{LabelDiff: LabelDiff{Added: TrashDiff.Added}}
+	for _, id := range ids {
+		if slices.Contains(fromInbox, id) {
+			toInbox.IDs = append(toInbox.IDs, id)
+		} else {
+			elsewhere.IDs = append(elsewhere.IDs, id)
+		}
+	}
+	return s.Revert(ctx, []LabelChange{toInbox, elsewhere})
+}
+
+// trashedIDs lists the mails in the trash carrying the given marker.
+func (s *MailService) trashedIDs(ctx context.Context, marker string) ([]string, error) {
+	req := s.service().Users.Messages.
+		List(s.user).
+		LabelIds(trashLabel, marker).
+		IncludeSpamTrash(true).
+		MaxResults(maxResults).
+		Context(ctx)
+
+	var ids []string
+	var pageToken string
+	for {
+		if err := s.lim.WaitN(ctx, messagesListQuotaUsage); err != nil {
+			return nil, fmt.Errorf("cannot list trashed mails: %w", err)
+		}
+		resp, err := req.PageToken(pageToken).Do()
+		if err != nil {
+			return nil, fmt.Errorf("cannot list trashed mails: %w", err)
+		}
+		ids = append(ids, getIds(resp.Messages)...)
+		if resp.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+	return ids, nil
+}
@@ -0,0 +1,244 @@
+package inbox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+// AgeBucket is a slot of the age histogram, holding mails received up to
+// MaxAge ago, and after the previous bucket. The last one has no upper bound.
+type AgeBucket struct {
+	Name   string
+	MaxAge time.Duration
+}
+
+var AgeBuckets = []AgeBucket{
+	{Name: "1w", MaxAge: 7 * day},
+	{Name: "1m", MaxAge: 30 * day},
+	{Name: "3m", MaxAge: 90 * day},
+	{Name: "1y", MaxAge: 365 * day},
+	{Name: ">1y"},
+}
+
+// AgeHistogram counts the mails of a list falling into each of AgeBuckets.
+type AgeHistogram []int
+
+func newAgeHistogram(msgs []MessageRef, now time.Time) AgeHistogram {
+	h := make(AgeHistogram, len(AgeBuckets))
+	for _, msg := range msgs {
+		h[ageBucket(now.Sub(msg.Received))]++
+	}
+	return h
+}
+
+func ageBucket(age time.Duration) int {
+	for i, b := range AgeBuckets {
+		if b.MaxAge == 0 || age <= b.MaxAge {
+			return i
+		}
+	}
+	return len(AgeBuckets) - 1
+}
+
+// String renders the non empty buckets, e.g. "1w:3 1y:12".
+func (h AgeHistogram) String() string {
+	parts := make([]string, 0, len(h))
+	for i, n := range h {
+		if n > 0 {
+			parts = append(parts, fmt.Sprintf("%s:%d", AgeBuckets[i].Name, n))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// MessageRef is what a list keeps of each of its mails, enough to act on a
+// subset of them.
+type MessageRef struct {
+	ID             string
+	Received       time.Time
+	Size           int64
+	HasAttachments bool
+	// Gmail label IDs as of the scan, telling what an action changes
+	Labels []string
+}
+
+// OlderThan holds for mails received more than d before now.
+func OlderThan(d time.Duration, now time.Time) func(RawMail) bool {
+	cutoff := now.Add(-d)
+	return func(rm RawMail) bool {
+		return rm.Received.Before(cutoff)
+	}
+}
+
+// NewerThan holds for mails received at most d before now.
+func NewerThan(d time.Duration, now time.Time) func(RawMail) bool {
+	cutoff := now.Add(-d)
+	return func(rm RawMail) bool {
+		return !rm.Received.Before(cutoff)
+	}
+}
+
+// WithOlderThan only groups mails received more than d ago.
+func WithOlderThan(d time.Duration) GroupOpt {
+	return func(cfg *groupConfig) {
+		if d > 0 {
+			cfg.filters = append(cfg.filters, OlderThan(d, time.Now()))
+		}
+	}
+}
+
+// WithNewerThan only groups mails received within the last d.
+func WithNewerThan(d time.Duration) GroupOpt {
+	return func(cfg *groupConfig) {
+		if d > 0 {
+			cfg.filters = append(cfg.filters, NewerThan(d, time.Now()))
+		}
+	}
+}
+
+// ParseAge reads a duration as accepted by time.ParseDuration, or a number of
+// days, weeks, months or years such as "14d", "6mo" or "1y". Unlike
+// time.ParseDuration, a bare "m" means months as well, as minutes make little
+// sense for mail ages.
+func ParseAge(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	// longest suffixes first, so "mo" is not read as "m"
+	for _, u := range []struct {
+		suffix string
+		unit   time.Duration
+	}{
+		{"mo", 30 * day},
+		{"d", day},
+		{"w", 7 * day},
+		{"m", 30 * day},
+		{"y", 365 * day},
+	} {
+		num, ok := strings.CutSuffix(s, u.suffix)
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(num); err == nil && n >= 0 {
+			return time.Duration(n) * u.unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("unknown age %q", s)
+	}
+	return d, nil
+}
+
+// FormatAge renders d in the largest unit ParseAge accepts that fits it.
+func FormatAge(d time.Duration) string {
+	for _, u := range []struct {
+		suffix string
+		unit   time.Duration
+	}{
+		{"y", 365 * day},
+		{"m", 30 * day},
+		{"w", 7 * day},
+		{"d", day},
+	} {
+		if d >= u.unit && d%u.unit == 0 {
+			return fmt.Sprintf("%d%s", d/u.unit, u.suffix)
+		}
+	}
+	return d.String()
+}
+
+// OlderThan narrows the list down to the mails received more than d before
+// now, and whether any is left. Actions taking an age qualifier run on it.
+func (m MailingList) OlderThan(d time.Duration, now time.Time) (MailingList, bool) {
+	cutoff := now.Add(-d)
+	return m.keepMessages(func(msg MessageRef) bool {
+		return msg.Received.Before(cutoff)
+	})
+}
+
+// Without returns the list once the given mails have been dealt with, and
+// whether anything is left in it.
+func (m MailingList) Without(ids []string) (MailingList, bool) {
+	drop := idSet(ids)
+	return m.keepMessages(func(msg MessageRef) bool {
+		return !drop[msg.ID]
+	})
+}
+
+// Only narrows the list down to the given mails, and tells whether any of
+// them is in it.
+func (m MailingList) Only(ids []string) (MailingList, bool) {
+	keep := idSet(ids)
+	return m.keepMessages(func(msg MessageRef) bool {
+		return keep[msg.ID]
+	})
+}
+
+func idSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// keepMessages recomputes every count of the list, its members and templates,
+// out of the mails for which keep holds.
+func (m MailingList) keepMessages(keep func(MessageRef) bool) (MailingList, bool) {
+	msgs := make([]MessageRef, 0, len(m.Messages))
+	for _, msg := range m.Messages {
+		if keep(msg) {
+			msgs = append(msgs, msg)
+		}
+	}
+	m.Messages = msgs
+	m.UnreadMessagesIDs = make([]string, 0, len(msgs))
+	m.TotalBytes, m.WithAttachments = 0, 0
+	m.FirstSeen, m.LastSeen = time.Time{}, time.Time{}
+	for _, msg := range msgs {
+		m.UnreadMessagesIDs = append(m.UnreadMessagesIDs, msg.ID)
+		m.TotalBytes += msg.Size
+		if msg.HasAttachments {
+			m.WithAttachments++
+		}
+		if msg.Received.After(m.LastSeen) {
+			m.LastSeen = msg.Received
+		}
+		if m.FirstSeen.IsZero() || msg.Received.Before(m.FirstSeen) {
+			m.FirstSeen = msg.Received
+		}
+	}
+	m.TotalUnreads = len(msgs)
+	m.Ages = newAgeHistogram(msgs, time.Now())
+
+	if len(m.Members) > 0 {
+		members := make([]MailingList, 0, len(m.Members))
+		senders := make([]string, 0, len(m.Members))
+		for _, member := range m.Members {
+			if member, ok := member.keepMessages(keep); ok {
+				members = append(members, member)
+				senders = append(senders, member.From)
+			}
+		}
+		m.Members = members
+		m.Senders = senders
+		if len(senders) > 0 {
+			m.From = senders[0]
+		}
+	}
+	if len(m.Templates) > 0 {
+		templates := make([]MailingList, 0, len(m.Templates))
+		for _, t := range m.Templates {
+			if t, ok := t.keepMessages(keep); ok {
+				templates = append(templates, t)
+			}
+		}
+		m.Templates = templates
+	}
+	return m, m.TotalUnreads > 0
+}
@@ -0,0 +1,120 @@
+package inbox
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", 0},
+		{"14d", 14 * day},
+		{"2w", 14 * day},
+		{"6mo", 180 * day},
+		{"6m", 180 * day},
+		{"1y", 365 * day},
+		{"36h", 36 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseAge(tt.in)
+			if err != nil || got != tt.want {
+				t.Errorf("ParseAge(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+			}
+		})
+	}
+	for _, in := range []string{"soon", "-3d", "-1h"} {
+		if _, err := ParseAge(in); err == nil {
+			t.Errorf("ParseAge(%q) succeeded", in)
+		}
+	}
+}
+
+func TestOlderThanRecomputesList(t *testing.T) {
+	now := time.Now()
+	ref := func(id string, age time.Duration, size int64, attachments bool) MessageRef {
+		return MessageRef{ID: id, Received: now.Add(-age), Size: size, HasAttachments: attachments}
+	}
+	oldest := ref("a-1", 400*day, 100, true)
+	old := ref("b-1", 100*day, 200, false)
+	recent := ref("a-2", 10*day, 300, true)
+	newest := ref("c-1", 2*day, 400, false)
+
+	list := func(key string, msgs ...MessageRef) MailingList {
+		l := MailingList{Key: key, From: key, Messages: msgs, TotalUnreads: len(msgs)}
+		for _, msg := range msgs {
+			l.UnreadMessagesIDs = append(l.UnreadMessagesIDs, msg.ID)
+		}
+		return l
+	}
+	domain := list("shop.com", oldest, old, recent, newest)
+	domain.From = "c@shop.com"
+	domain.Members = []MailingList{
+		list("c@shop.com", newest),
+		list("a@shop.com", oldest, recent),
+		list("b@shop.com", old),
+	}
+	domain.Senders = []string{"c@shop.com", "a@shop.com", "b@shop.com"}
+	domain.Templates = []MailingList{
+		list("Your order #", oldest, recent),
+		list("Weekly deals", old, newest),
+		list("New arrivals", newest),
+	}
+
+	got, ok := domain.OlderThan(30*day, now)
+	if !ok {
+		t.Fatal("no mail left older than 30 days")
+	}
+
+	if got.TotalUnreads != 2 || !reflect.DeepEqual(got.UnreadMessagesIDs, []string{"a-1", "b-1"}) {
+		t.Errorf("kept %d mails %v, want 2 mails [a-1 b-1]", got.TotalUnreads, got.UnreadMessagesIDs)
+	}
+	if !reflect.DeepEqual(got.Messages, []MessageRef{oldest, old}) {
+		t.Errorf("Messages = %+v, want the two oldest", got.Messages)
+	}
+	if got.TotalBytes != 300 {
+		t.Errorf("TotalBytes = %d, want 300", got.TotalBytes)
+	}
+	if got.WithAttachments != 1 {
+		t.Errorf("WithAttachments = %d, want 1", got.WithAttachments)
+	}
+	if !got.FirstSeen.Equal(oldest.Received) || !got.LastSeen.Equal(old.Received) {
+		t.Errorf("seen from %v to %v, want %v to %v", got.FirstSeen, got.LastSeen, oldest.Received, old.Received)
+	}
+	if want := (AgeHistogram{0, 0, 0, 1, 1}); !reflect.DeepEqual(got.Ages, want) {
+		t.Errorf("Ages = %v, want %v", got.Ages, want)
+	}
+
+	var members []string
+	for _, m := range got.Members {
+		members = append(members, m.Key)
+		if m.TotalUnreads != 1 || len(m.Messages) != 1 {
+			t.Errorf("member %s kept %d mails, want 1", m.Key, m.TotalUnreads)
+		}
+	}
+	if want := []string{"a@shop.com", "b@shop.com"}; !reflect.DeepEqual(members, want) {
+		t.Errorf("Members = %v, want %v", members, want)
+	}
+	if !reflect.DeepEqual(got.Senders, members) || got.From != "a@shop.com" {
+		t.Errorf("Senders = %v and From = %s, want the members left, from a@shop.com", got.Senders, got.From)
+	}
+
+	var templates []string
+	for _, tmpl := range got.Templates {
+		templates = append(templates, tmpl.Key)
+		if tmpl.TotalUnreads != 1 {
+			t.Errorf("template %s kept %d mails, want 1", tmpl.Key, tmpl.TotalUnreads)
+		}
+	}
+	if want := []string{"Your order #", "Weekly deals"}; !reflect.DeepEqual(templates, want) {
+		t.Errorf("Templates = %v, want %v", templates, want)
+	}
+
+	if _, ok := domain.OlderThan(2*365*day, now); ok {
+		t.Error("mails left older than two years")
+	}
+}
@@ -0,0 +1,45 @@
+package inbox
+
+import (
+	"sync"
+	"time"
+)
+
+// Attempt is the outcome of a single unsubscribe request.
+type Attempt struct {
+	Method UnsubscribeMethod
+	Target string
+	At     time.Time
+	// HTTP status code of the final response, zero when none was received
+	Status int
+	// human readable result, such as the landing page title or the ID of
+	// the sent mail
+	Detail string
+	// ID of the unsubscribe mail sent, for mailto only
+	MessageID string
+	Err       error
+}
+
+func (a Attempt) Succeeded() bool {
+	return a.Err == nil
+}
+
+// attemptLog keeps every attempt made by an unsubscriber. Do runs off the UI
+// goroutine, hence the lock.
+type attemptLog struct {
+	mu       sync.Mutex
+	attempts []Attempt
+}
+
+func (l *attemptLog) record(a Attempt) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.attempts = append(l.attempts, a)
+	return a.Err
+}
+
+func (l *attemptLog) Attempts() []Attempt {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Attempt(nil), l.attempts...)
+}
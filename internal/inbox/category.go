@@ -0,0 +1,89 @@
+package inbox
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Category is one of the Gmail inbox tabs, as told by its system label.
+type Category string
+
+const (
+	// no category filter, every tab at once
+	AllCategories      Category = ""
+	CategoryPersonal   Category = "CATEGORY_PERSONAL"
+	CategorySocial     Category = "CATEGORY_SOCIAL"
+	CategoryPromotions Category = "CATEGORY_PROMOTIONS"
+	CategoryUpdates    Category = "CATEGORY_UPDATES"
+	CategoryForums     Category = "CATEGORY_FORUMS"
+	// mails Gmail sorted into no tab, which is not a label of its own
+	Uncategorized Category = "UNCATEGORIZED"
+)
+
+// Categories lists every tab in the order Gmail shows them, then the mails
+// in none of them.
+var Categories = []Category{
+	CategoryPersonal,
+	CategorySocial,
+	CategoryPromotions,
+	CategoryUpdates,
+	CategoryForums,
+	Uncategorized,
+}
+
+func (c Category) String() string {
+	if c == AllCategories {
+		return "All"
+	}
+	name := strings.ToLower(strings.TrimPrefix(string(c), "CATEGORY_"))
+	if name == "personal" {
+		// that is how Gmail names the tab
+		return "Primary"
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func ParseCategory(s string) (Category, error) {
+	if s == "" || strings.EqualFold(s, AllCategories.String()) {
+		return AllCategories, nil
+	}
+	for _, c := range Categories {
+		if strings.EqualFold(s, c.String()) || strings.EqualFold(s, string(c)) {
+			return c, nil
+		}
+	}
+	return AllCategories, fmt.Errorf("unknown category %q", s)
+}
+
+// Next cycles through every category, starting over with all of them.
+func (c Category) Next() Category {
+	idx := slices.Index(Categories, c)
+	if idx+1 >= len(Categories) {
+		return AllCategories
+	}
+	return Categories[idx+1]
+}
+
+// Category returns the inbox tab the mail was sorted into, Uncategorized if
+// none.
+func (rm RawMail) Category() Category {
+	for _, l := range rm.Labels {
+		if c := Category(l); c != Uncategorized && slices.Contains(Categories, c) {
+			return c
+		}
+	}
+	return Uncategorized
+}
+
+// WithCategory keeps only the mails Gmail sorted into the given tab.
+func WithCategory(c Category) GroupOpt {
+	return func(cfg *groupConfig) {
+		if c == AllCategories {
+			return
+		}
+		cfg.filters = append(cfg.filters, func(rm RawMail) bool {
+			return rm.Category() == c
+		})
+	}
+}
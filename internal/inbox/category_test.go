@@ -0,0 +1,79 @@
+package inbox
+
+import "testing"
+
+func TestGroupByCategory(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels []string
+		want   string
+	}{
+		{"tab", []string{"UNREAD", string(CategoryPromotions)}, "Promotions"},
+		{"primary", []string{string(CategoryPersonal)}, "Primary"},
+		{"no tab", []string{"UNREAD", "INBOX"}, "Uncategorized"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GroupByCategory.of(RawMail{From: "news@example.com", Labels: tt.labels})
+			if got != tt.want {
+				t.Errorf("grouped under %q, want %q", got, tt.want)
+			}
+			if got == AllCategories.String() {
+				t.Errorf("grouped under the name of the unfiltered tab")
+			}
+		})
+	}
+}
+
+func TestParseCategory(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Category
+	}{
+		{"", AllCategories},
+		{"all", AllCategories},
+		{"primary", CategoryPersonal},
+		{"Promotions", CategoryPromotions},
+		{"CATEGORY_FORUMS", CategoryForums},
+		{"uncategorized", Uncategorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseCategory(tt.in)
+			if err != nil || got != tt.want {
+				t.Errorf("ParseCategory(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+			}
+		})
+	}
+	if _, err := ParseCategory("inbox"); err == nil {
+		t.Errorf("parsed an unknown category")
+	}
+}
+
+func TestNextCyclesThroughUncategorized(t *testing.T) {
+	c := AllCategories
+	var seen []Category
+	for range len(Categories) + 1 {
+		c = c.Next()
+		seen = append(seen, c)
+	}
+	if seen[len(seen)-2] != Uncategorized || seen[len(seen)-1] != AllCategories {
+		t.Errorf("Next() cycles through %v, want Uncategorized last before all", seen)
+	}
+}
+
+func TestWithCategoryUncategorized(t *testing.T) {
+	cfg := &groupConfig{}
+	WithCategory(Uncategorized)(cfg)
+	for _, tt := range []struct {
+		labels []string
+		want   bool
+	}{
+		{[]string{"INBOX"}, true},
+		{[]string{"INBOX", string(CategoryUpdates)}, false},
+	} {
+		if got := cfg.filters[0](RawMail{Labels: tt.labels}); got != tt.want {
+			t.Errorf("mail labeled %v kept: %v, want %v", tt.labels, got, tt.want)
+		}
+	}
+}
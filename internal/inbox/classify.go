@@ -0,0 +1,250 @@
+package inbox
+
+import (
+	"net/mail"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Kind is what sort of sender a mail most likely comes from.
+type Kind int
+
+const (
+	KindUnknown Kind = iota
+	// newsletters and discussion lists
+	KindList
+	// marketing and other mass mailings
+	KindBulk
+	// machine generated mail: receipts, alerts, bots
+	KindNotification
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindList:
+		return "list"
+	case KindBulk:
+		return "bulk"
+	case KindNotification:
+		return "notification"
+	default:
+		return "unknown"
+	}
+}
+
+// Rule is a single heuristic. Weight is how confident a match alone makes us
+// that the mail is of the given kind, between 0 and 1.
+type Rule struct {
+	Name   string
+	Kind   Kind
+	Weight float64
+	Match  func(RawMail) bool
+}
+
+// Classification is the verdict for a mail, or a group of them.
+type Classification struct {
+	Kind       Kind
+	Confidence float64
+	// names of the rules that matched for the winning kind
+	Rules []string
+}
+
+type Classifier struct {
+	rules []Rule
+}
+
+func NewClassifier(rules ...Rule) *Classifier {
+	return &Classifier{rules: rules}
+}
+
+// DefaultClassifier uses every built-in rule.
+var DefaultClassifier = NewClassifier(DefaultRules...)
+
+var (
+	noReplyRe = regexp.MustCompile(`^(no-?reply|do-?not-?reply|notifications?|alerts?|mailer-daemon|bounces?)([+._-]|$)`)
+
+	// headers only set by email service providers
+	espHeaderKeys = []string{
+		"x-mailgun-sid",
+		"x-mailgun-tag",
+		"x-sg-eid",
+		"x-sg-id",
+		"feedback-id",
+		"x-ses-outgoing",
+		"x-mc-user",
+		"x-mandrill-user",
+		"x-campaign",
+		"x-campaignid",
+		"x-mailchimp-campaign",
+		"x-sfmc-stack",
+		"x-marketo-id",
+		"x-hubspot-id",
+		"x-pm-message-id",
+		"x-postmark-server",
+		"x-sparkpost-id",
+		"x-msys-api",
+	}
+)
+
+var DefaultRules = []Rule{
+	{
+		Name:   "precedence-list",
+		Kind:   KindList,
+		Weight: 0.7,
+		Match:  headerEquals("precedence", "list"),
+	},
+	{
+		Name:   "precedence-bulk",
+		Kind:   KindBulk,
+		Weight: 0.6,
+		Match:  headerEquals("precedence", "bulk", "junk"),
+	},
+	{
+		Name:   "list-id",
+		Kind:   KindList,
+		Weight: 0.8,
+		Match:  hasHeader(listIDHeaderKey),
+	},
+	{
+		Name:   "list-unsubscribe",
+		Kind:   KindList,
+		Weight: 0.6,
+		Match:  hasHeader(unsubscribeHeaderKey),
+	},
+	{
+		Name:   "auto-submitted",
+		Kind:   KindNotification,
+		Weight: 0.7,
+		Match: func(rm RawMail) bool {
+			v := firstHeader(rm, "auto-submitted")
+			return v != "" && v != "no"
+		},
+	},
+	{
+		Name:   "esp-fingerprint",
+		Kind:   KindBulk,
+		Weight: 0.5,
+		Match: func(rm RawMail) bool {
+			return slices.ContainsFunc(espHeaderKeys, func(k string) bool {
+				return hasHeader(k)(rm)
+			})
+		},
+	},
+	{
+		Name:   "noreply-sender",
+		Kind:   KindNotification,
+		Weight: 0.5,
+		Match: func(rm RawMail) bool {
+			local, _, _ := strings.Cut(strings.ToLower(rm.From), "@")
+			return noReplyRe.MatchString(local)
+		},
+	},
+	{
+		Name:   "return-path-mismatch",
+		Kind:   KindBulk,
+		Weight: 0.3,
+		Match:  returnPathMismatch,
+	},
+}
+
+// Classify scores rm against every rule. Matches for the same kind add up as
+// independent evidence, and the kind with the highest confidence wins.
+func (c *Classifier) Classify(rm RawMail) Classification {
+	doubts := make(map[Kind]float64)
+	rules := make(map[Kind][]string)
+	for _, r := range c.rules {
+		if !r.Match(rm) {
+			continue
+		}
+		if _, ok := doubts[r.Kind]; !ok {
+			doubts[r.Kind] = 1
+		}
+		doubts[r.Kind] *= 1 - r.Weight
+		rules[r.Kind] = append(rules[r.Kind], r.Name)
+	}
+
+	var best Classification
+	for k, doubt := range doubts {
+		confidence := 1 - doubt
+		if confidence > best.Confidence || (confidence == best.Confidence && k < best.Kind) {
+			best = Classification{Kind: k, Confidence: confidence, Rules: rules[k]}
+		}
+	}
+	return best
+}
+
+// classifyGroup picks the kind most of the mails are confident about, with
+// its confidence averaged over the whole group.
+func (c *Classifier) classifyGroup(mails []RawMail) Classification {
+	if len(mails) == 0 {
+		return Classification{}
+	}
+
+	scores := make(map[Kind]float64)
+	rules := make(map[Kind][]string)
+	for _, rm := range mails {
+		cl := c.Classify(rm)
+		if cl.Kind == KindUnknown {
+			continue
+		}
+		scores[cl.Kind] += cl.Confidence
+		for _, r := range cl.Rules {
+			if !slices.Contains(rules[cl.Kind], r) {
+				rules[cl.Kind] = append(rules[cl.Kind], r)
+			}
+		}
+	}
+
+	var best Classification
+	for k, score := range scores {
+		confidence := score / float64(len(mails))
+		if confidence > best.Confidence || (confidence == best.Confidence && k < best.Kind) {
+			best = Classification{Kind: k, Confidence: confidence, Rules: rules[k]}
+		}
+	}
+	return best
+}
+
+// WithClassifier replaces the rules used to tell what kind of sender each
+// list is, DefaultClassifier if not given.
+func WithClassifier(c *Classifier) GroupOpt {
+	return func(cfg *groupConfig) {
+		cfg.classifier = c
+	}
+}
+
+func hasHeader(name string) func(RawMail) bool {
+	return func(rm RawMail) bool {
+		return len(rm.Headers[name]) > 0
+	}
+}
+
+func headerEquals(name string, values ...string) func(RawMail) bool {
+	return func(rm RawMail) bool {
+		return slices.Contains(values, firstHeader(rm, name))
+	}
+}
+
+func firstHeader(rm RawMail, name string) string {
+	vals := rm.Headers[name]
+	if len(vals) == 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(vals[0]))
+}
+
+// returnPathMismatch spots bounces handled by someone other than the sender,
+// which is how most mass mailing platforms operate.
+func returnPathMismatch(rm RawMail) bool {
+	rp := firstHeader(rm, "return-path")
+	if rp == "" || rp == "<>" {
+		return false
+	}
+	addr, err := mail.ParseAddress(rp)
+	if err != nil {
+		return false
+	}
+	from, bounce := RegistrableDomain(rm.From), RegistrableDomain(addr.Address)
+	return from != "" && bounce != "" && from != bounce
+}
@@ -0,0 +1,154 @@
+package inbox
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func fixture(from string, headers map[string]string) RawMail {
+	rm := RawMail{From: from, Headers: make(map[string][]string)}
+	for k, v := range headers {
+		rm.Headers[k] = []string{v}
+	}
+	return rm
+}
+
+func TestDefaultRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		mail    RawMail
+		matches []string
+	}{
+		{"precedence list", fixture("news@example.com", map[string]string{"precedence": "List"}), []string{"precedence-list"}},
+		{"precedence bulk", fixture("news@example.com", map[string]string{"precedence": "bulk"}), []string{"precedence-bulk"}},
+		{"precedence junk", fixture("news@example.com", map[string]string{"precedence": " junk "}), []string{"precedence-bulk"}},
+		{"list-id", fixture("news@example.com", map[string]string{"list-id": "<news.example.com>"}), []string{"list-id"}},
+		{"list-unsubscribe", fixture("news@example.com", map[string]string{"list-unsubscribe": "<mailto:u@example.com>"}), []string{"list-unsubscribe"}},
+		{"auto-submitted", fixture("robot@example.com", map[string]string{"auto-submitted": "auto-generated"}), []string{"auto-submitted"}},
+		{"auto-submitted no", fixture("someone@example.com", map[string]string{"auto-submitted": "no"}), nil},
+		{"mailgun", fixture("news@example.com", map[string]string{"x-mailgun-sid": "abc"}), []string{"esp-fingerprint"}},
+		{"sendgrid", fixture("news@example.com", map[string]string{"x-sg-eid": "abc"}), []string{"esp-fingerprint"}},
+		{"feedback-id", fixture("news@example.com", map[string]string{"feedback-id": "1:2:3"}), []string{"esp-fingerprint"}},
+		{"noreply", fixture("noreply@example.com", nil), []string{"noreply-sender"}},
+		{"no-reply", fixture("No-Reply@example.com", nil), []string{"noreply-sender"}},
+		{"donotreply", fixture("donotreply@example.com", nil), []string{"noreply-sender"}},
+		{"notifications tagged", fixture("notifications+abc@example.com", nil), []string{"noreply-sender"}},
+		{"noreply lookalike", fixture("noreplyguy@example.com", nil), nil},
+		{"return-path mismatch", fixture("news@example.com", map[string]string{"return-path": "<bounce@mailer.net>"}), []string{"return-path-mismatch"}},
+		{"return-path subdomain", fixture("news@example.com", map[string]string{"return-path": "<bounce@mail.example.com>"}), nil},
+		{"return-path null", fixture("news@example.com", map[string]string{"return-path": "<>"}), nil},
+		{"plain mail", fixture("friend@example.com", map[string]string{"subject": "hi"}), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range DefaultRules {
+				if r.Match(tt.mail) {
+					got = append(got, r.Name)
+				}
+			}
+			if !slices.Equal(got, tt.matches) {
+				t.Errorf("matched %v, want %v", got, tt.matches)
+			}
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name       string
+		mail       RawMail
+		kind       Kind
+		confidence float64
+	}{
+		{
+			name:       "single rule",
+			mail:       fixture("news@example.com", map[string]string{"list-id": "<news.example.com>"}),
+			kind:       KindList,
+			confidence: 0.8,
+		},
+		{
+			name: "evidence adds up",
+			mail: fixture("news@example.com", map[string]string{
+				"list-id":          "<news.example.com>",
+				"list-unsubscribe": "<mailto:u@example.com>",
+			}),
+			kind:       KindList,
+			confidence: 1 - 0.2*0.4,
+		},
+		{
+			name: "strongest kind wins",
+			mail: fixture("noreply@example.com", map[string]string{
+				"precedence":     "bulk",
+				"auto-submitted": "auto-generated",
+			}),
+			kind:       KindNotification,
+			confidence: 1 - 0.3*0.5,
+		},
+		{
+			name: "unknown",
+			mail: fixture("friend@example.com", nil),
+			kind: KindUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DefaultClassifier.Classify(tt.mail)
+			if got.Kind != tt.kind || math.Abs(got.Confidence-tt.confidence) > 1e-9 {
+				t.Errorf("got %s %.3f, want %s %.3f", got.Kind, got.Confidence, tt.kind, tt.confidence)
+			}
+		})
+	}
+}
+
+func TestClassifyGroup(t *testing.T) {
+	list := fixture("news@example.com", map[string]string{"list-id": "<news.example.com>"})
+	plain := fixture("news@example.com", nil)
+
+	tests := []struct {
+		name       string
+		mails      []RawMail
+		kind       Kind
+		confidence float64
+	}{
+		{"empty", nil, KindUnknown, 0},
+		{"all unknown", []RawMail{plain, plain}, KindUnknown, 0},
+		{"averaged over the group", []RawMail{list, plain}, KindList, 0.4},
+		{"every mail agrees", []RawMail{list, list}, KindList, 0.8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DefaultClassifier.classifyGroup(tt.mails)
+			if got.Kind != tt.kind || math.Abs(got.Confidence-tt.confidence) > 1e-9 {
+				t.Errorf("got %s %.3f, want %s %.3f", got.Kind, got.Confidence, tt.kind, tt.confidence)
+			}
+		})
+	}
+}
+
+func TestDefaultViewIncludesBulk(t *testing.T) {
+	newsletter := fixture("news@shop.com", map[string]string{
+		"precedence":    "bulk",
+		"x-mailgun-sid": "abc",
+	})
+	newsletter.ID = "1"
+	personal := fixture("friend@example.com", nil)
+	personal.ID = "2"
+	notification := fixture("noreply@bank.com", map[string]string{"auto-submitted": "auto-generated"})
+	notification.ID = "3"
+
+	lists := GetMailingList(RawMailList{newsletter, personal, notification})
+	if len(lists) != 1 || lists[0].From != "news@shop.com" {
+		t.Fatalf("got %d lists, want only the header-less newsletter", len(lists))
+	}
+	if lists[0].Classification.Kind != KindBulk {
+		t.Errorf("newsletter classified as %s, want bulk", lists[0].Classification.Kind)
+	}
+	if lists[0].UnsubscribeAvailable() {
+		t.Error("newsletter without List-Unsubscribe offers an unsubscribe")
+	}
+}
@@ -0,0 +1,150 @@
+package inbox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+const (
+	userAgent = "GheeMail/1.0"
+
+	// whole request, including redirects and reading the body
+	unsubscribeTimeout = 15 * time.Second
+	dialTimeout        = 5 * time.Second
+	maxRedirects       = 5
+
+	// bodies are drained up to this size so the connection can be reused
+	maxDrainSize = 4 << 10
+)
+
+var (
+	ErrForbiddenAddress = errors.New("refusing to connect to a non-public address")
+	ErrInsecureRedirect = errors.New("refusing to follow a redirect off https")
+	ErrTooManyRedirects = errors.New("too many redirects")
+)
+
+// ranges not publicly routable, or that reach into one that is not, from the
+// IANA special-purpose address registries
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // this network
+	netip.MustParsePrefix("10.0.0.0/8"),      // private
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("127.0.0.0/8"),     // loopback
+	netip.MustParsePrefix("169.254.0.0/16"),  // link-local, cloud metadata
+	netip.MustParsePrefix("172.16.0.0/12"),   // private
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("192.88.99.0/24"),  // 6to4 relay anycast
+	netip.MustParsePrefix("192.168.0.0/16"),  // private
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("224.0.0.0/4"),     // multicast
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved and broadcast
+
+	netip.MustParsePrefix("::/96"),          // unspecified, loopback, IPv4-compatible
+	netip.MustParsePrefix("::ffff:0:0/96"),  // IPv4-mapped
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64, maps to any IPv4 address
+	netip.MustParsePrefix("64:ff9b:1::/48"), // local-use NAT64
+	netip.MustParsePrefix("100::/64"),       // discard
+	netip.MustParsePrefix("2001::/32"),      // Teredo
+	netip.MustParsePrefix("2001:db8::/32"),  // documentation
+	netip.MustParsePrefix("2002::/16"),      // 6to4, embeds any IPv4 address
+	netip.MustParsePrefix("fc00::/7"),       // unique local
+	netip.MustParsePrefix("fe80::/10"),      // link-local
+	netip.MustParsePrefix("ff00::/8"),       // multicast
+}
+
+// unsubscribeClient is the only client allowed to follow URLs found in mail
+// headers. Those are attacker controlled, so it refuses to reach anything but
+// public addresses over https and bounds how long a sender can hold us.
+var unsubscribeClient = newUnsubscribeClient()
+
+func newUnsubscribeClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: dialTimeout,
+		// checked once the name is resolved, right before connecting, so DNS
+		// answers cannot sneak an internal address in
+		Control: refuseNonPublicAddress,
+	}
+
+	return &http.Client{
+		Timeout: unsubscribeTimeout,
+		Transport: &http.Transport{
+			// a proxy would connect on our behalf and skip the dialer checks
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			TLSHandshakeTimeout:   dialTimeout,
+			ResponseHeaderTimeout: unsubscribeTimeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return ErrTooManyRedirects
+			}
+			if req.URL.Scheme != "https" {
+				return fmt.Errorf("%w: %s", ErrInsecureRedirect, req.URL.Redacted())
+			}
+			return nil
+		},
+	}
+}
+
+func refuseNonPublicAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !isPublicAddress(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+func isPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap().WithZone("")
+	if !addr.IsValid() {
+		return false
+	}
+	for _, p := range nonPublicPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// doUnsubscribeRequest sends req through the unsubscribe client, failing on
+// any non 2xx answer. Successful responses are handed to read, if set, before
+// the body gets closed.
+func doUnsubscribeRequest(req *http.Request, read func(*http.Response) error) (int, error) {
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := unsubscribeClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainSize)) //nolint:errcheck
+		resp.Body.Close()                                            //nolint:errcheck
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("failed req, status %s", resp.Status)
+	}
+	if read != nil {
+		if err := read(resp); err != nil {
+			return resp.StatusCode, err
+		}
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,128 @@
+package inbox
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+)
+
+func TestRefuseNonPublicAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		public  bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"0.0.0.0:443", false},
+		{"0.1.2.3:443", false},
+		{"10.1.2.3:443", false},
+		{"100.64.0.1:443", false},
+		{"127.0.0.1:443", false},
+		{"169.254.169.254:80", false},
+		{"172.16.0.1:443", false},
+		{"192.0.0.8:443", false},
+		{"192.168.1.1:443", false},
+		{"198.18.0.1:443", false},
+		{"198.19.255.255:443", false},
+		{"224.0.0.1:443", false},
+		{"255.255.255.255:443", false},
+		{"[::]:443", false},
+		{"[::1]:443", false},
+		{"[::ffff:10.0.0.1]:443", false},
+		{"[64:ff9b::a00:1]:443", false},
+		{"[64:ff9b::7f00:1]:443", false},
+		{"[2002:a00:1::]:443", false},
+		{"[fc00::1]:443", false},
+		{"[fe80::1%eth0]:443", false},
+		{"[ff02::1]:443", false},
+		{"example.com:443", false},
+		{"no-port", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := refuseNonPublicAddress("tcp", tt.address, nil)
+			if tt.public && err != nil {
+				t.Errorf("refused a public address: %v", err)
+			}
+			if !tt.public && !errors.Is(err, ErrForbiddenAddress) {
+				t.Errorf("got %v, want %v", err, ErrForbiddenAddress)
+			}
+		})
+	}
+}
+
+// testClient is the unsubscribe client, trusting srv and allowed to reach it
+// on loopback, while every other address goes through the usual checks.
+func testClient(srv *httptest.Server) *http.Client {
+	client := newUnsubscribeClient()
+	transport := client.Transport.(*http.Transport)
+	transport.TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig
+	dialer := &net.Dialer{
+		Timeout: dialTimeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			if address == srv.Listener.Addr().String() {
+				return nil
+			}
+			return refuseNonPublicAddress(network, address, c)
+		},
+	}
+	transport.DialContext = dialer.DialContext
+	return client
+}
+
+func TestUnsubscribeClientRedirects(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/redirect":
+			http.Redirect(w, r, srv.URL+"/ok", http.StatusFound)
+		case "/insecure":
+			http.Redirect(w, r, "http://example.com/unsubscribe", http.StatusFound)
+		case "/private":
+			http.Redirect(w, r, "https://169.254.169.254/latest/meta-data", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, srv.URL+"/loop", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+	client := testClient(srv)
+
+	tests := []struct {
+		path string
+		want error
+	}{
+		{"/ok", nil},
+		{"/redirect", nil},
+		{"/insecure", ErrInsecureRedirect},
+		{"/private", ErrForbiddenAddress},
+		{"/loop", ErrTooManyRedirects},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := client.Get(srv.URL + tt.path)
+			if err == nil {
+				resp.Body.Close() //nolint:errcheck
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnsubscribeClientRefusesLoopback(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	_, err := unsubscribeClient.Get(srv.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("got %v, want %v", err, ErrForbiddenAddress)
+	}
+}
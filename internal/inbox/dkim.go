@@ -0,0 +1,245 @@
+package inbox
+
+import (
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	authResultsHeaderKey   = "authentication-results"
+	dkimSignatureHeaderKey = "dkim-signature"
+	fromHeaderKey          = "from"
+)
+
+// only results stamped by the receiving server can be trusted, anything below
+// them was written by whoever sent the mail
+var trustedAuthServIDs = []string{"mx.google.com"}
+
+var headerCommentRe = regexp.MustCompile(`\([^)]*\)`)
+
+// DKIMAlignment tells whether a mail carries a valid DKIM signature, aligned
+// with its From domain, covering both headers RFC 8058 requires for one-click
+// unsubscribes.
+type DKIMAlignment struct {
+	Aligned bool
+	// signing domain of the aligned signature
+	Domain string
+	// why the mail is not aligned, empty when it is
+	Reason string
+}
+
+type authResult struct {
+	method string
+	result string
+	props  map[string]string
+}
+
+type dkimSignature struct {
+	domain string
+	signed []string
+	b      string
+}
+
+// CheckDKIMAlignment relies on the Authentication-Results header added by
+// Gmail for the signature verdict, and checks locally that the passing
+// signature is aligned with From and signs List-Unsubscribe and
+// List-Unsubscribe-Post. Otherwise, anyone could append those headers to a
+// forged or replayed mail and make us POST wherever they please.
+//
+// A signature naming a header once only covers its bottom-most copy, while
+// we read the topmost one, so every copy of From and the unsubscribe headers
+// must be signed.
+func CheckDKIMAlignment(headers map[string][]string) DKIMAlignment {
+	return checkDKIMAlignment(headers, fromHeaderKey, unsubscribeHeaderKey, postUnsubscribeHeaderKey)
+}
+
+// checkDKIMAlignment looks for a passing signature aligned with From and
+// covering every copy of the signed headers.
+func checkDKIMAlignment(headers map[string][]string, signed ...string) DKIMAlignment {
+	fromDomain := senderDomain(headers)
+	if fromDomain == "" {
+		return DKIMAlignment{Reason: "no sender domain"}
+	}
+
+	results, ok := trustedAuthResults(headers[authResultsHeaderKey])
+	if !ok {
+		return DKIMAlignment{Reason: "no trusted Authentication-Results"}
+	}
+
+	signatures := parseDKIMSignatures(headers[dkimSignatureHeaderKey])
+
+	reason := "no passing DKIM signature"
+	for _, r := range results {
+		if r.method != "dkim" || r.result != "pass" {
+			continue
+		}
+
+		domain := r.props["header.d"]
+		if domain == "" {
+			// Gmail only reports the identity, whose domain part must be
+			// the signing domain or one of its subdomains
+			_, domain, _ = strings.Cut(r.props["header.i"], "@")
+		}
+		domain = strings.ToLower(domain)
+		if domain == "" {
+			continue
+		}
+
+		// the signature is told apart by the start of its b= tag only, so
+		// anyone can prepend a forged copy signing other headers: refuse to
+		// pick one unless the result points at a single signature
+		b := r.props["header.b"]
+		if b == "" {
+			reason = "DKIM result by " + domain + " does not identify its signature"
+			continue
+		}
+		var matching []dkimSignature
+		for _, s := range signatures {
+			if s.matches(domain, b) {
+				matching = append(matching, s)
+			}
+		}
+		if len(matching) == 0 {
+			reason = "no DKIM signature by " + domain + " found"
+			continue
+		}
+		if len(matching) > 1 {
+			reason = "several DKIM signatures by " + domain + " match the one verified"
+			continue
+		}
+		sig := matching[0]
+
+		if !alignedDomains(sig.domain, fromDomain) {
+			reason = "DKIM signature by " + sig.domain + " not aligned with " + fromDomain
+			continue
+		}
+
+		if h, ok := sig.covers(headers, signed...); !ok {
+			reason = "DKIM signature by " + sig.domain + " does not cover every " + textproto.CanonicalMIMEHeaderKey(h) + " header"
+			continue
+		}
+
+		return DKIMAlignment{Aligned: true, Domain: sig.domain}
+	}
+
+	return DKIMAlignment{Reason: reason}
+}
+
+func senderDomain(headers map[string][]string) string {
+	from := headers[fromHeaderKey]
+	if len(from) == 0 {
+		return ""
+	}
+	addr, err := mail.ParseAddress(from[0])
+	if err != nil {
+		return ""
+	}
+	_, domain, _ := strings.Cut(addr.Address, "@")
+	return strings.ToLower(domain)
+}
+
+// alignedDomains implements DMARC relaxed alignment: both domains must share
+// the same organizational domain.
+func alignedDomains(a, b string) bool {
+	if a == b {
+		return true
+	}
+	org := RegistrableDomain(a)
+	return org != "" && org == RegistrableDomain(b)
+}
+
+// trustedAuthResults returns the results of the topmost Authentication-Results
+// header written by a trusted server.
+func trustedAuthResults(values []string) ([]authResult, bool) {
+	for _, v := range values {
+		parts := strings.Split(headerCommentRe.ReplaceAllString(v, ""), ";")
+		servID := strings.Fields(parts[0])
+		if len(servID) == 0 || !slices.Contains(trustedAuthServIDs, strings.ToLower(servID[0])) {
+			continue
+		}
+
+		results := make([]authResult, 0, len(parts)-1)
+		for _, p := range parts[1:] {
+			fields := strings.Fields(p)
+			if len(fields) == 0 {
+				continue
+			}
+			method, result, ok := strings.Cut(fields[0], "=")
+			if !ok {
+				continue
+			}
+			r := authResult{
+				method: strings.ToLower(method),
+				result: strings.ToLower(result),
+				props:  make(map[string]string),
+			}
+			for _, f := range fields[1:] {
+				if k, v, ok := strings.Cut(f, "="); ok {
+					r.props[strings.ToLower(k)] = v
+				}
+			}
+			results = append(results, r)
+		}
+		return results, true
+	}
+	return nil, false
+}
+
+func parseDKIMSignatures(values []string) []dkimSignature {
+	signatures := make([]dkimSignature, 0, len(values))
+	for _, v := range values {
+		var sig dkimSignature
+		for _, tag := range strings.Split(v, ";") {
+			name, val, ok := strings.Cut(tag, "=")
+			if !ok {
+				continue
+			}
+			// folding whitespace is allowed anywhere within tag values
+			val = strings.Join(strings.Fields(val), "")
+			switch strings.TrimSpace(name) {
+			case "d":
+				sig.domain = strings.ToLower(val)
+			case "h":
+				for _, h := range strings.Split(val, ":") {
+					sig.signed = append(sig.signed, strings.ToLower(h))
+				}
+			case "b":
+				sig.b = val
+			}
+		}
+		signatures = append(signatures, sig)
+	}
+	return signatures
+}
+
+// matches tells whether this is the signature a result refers to. Gmail
+// reports the first characters of the signature, which tell apart several
+// signatures from the same domain. The domain reported is either the signing
+// domain or, when taken from the identity, one of its subdomains.
+func (s dkimSignature) matches(domain, b string) bool {
+	if s.domain == "" || (domain != s.domain && !strings.HasSuffix(domain, "."+s.domain)) {
+		return false
+	}
+	return strings.HasPrefix(s.b, b)
+}
+
+// covers tells whether the signature signs every copy of the named headers
+// found in headers, that is, it lists each of them at least as many times as
+// it appears. Otherwise, it returns the first header left unsigned.
+func (s dkimSignature) covers(headers map[string][]string, names ...string) (string, bool) {
+	for _, name := range names {
+		signed := 0
+		for _, h := range s.signed {
+			if h == name {
+				signed++
+			}
+		}
+		if signed == 0 || signed < len(headers[name]) {
+			return name, false
+		}
+	}
+	return "", true
+}
@@ -0,0 +1,174 @@
+package inbox
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	genuineSignature = "v=1; a=rsa-sha256; d=example.com; s=s1; h=from:subject:list-unsubscribe:list-unsubscribe-post; bh=abc; b=AbCdEfGh12345678"
+	// signs both copies of a List-Unsubscribe header found twice
+	twiceSignedSignature = "v=1; a=rsa-sha256; d=example.com; s=s1; h=from:subject:list-unsubscribe:list-unsubscribe:list-unsubscribe-post; bh=abc; b=AbCdEfGh12345678"
+	forgedSignature      = "v=1; a=rsa-sha256; d=example.com; s=s1; h=from:subject; bh=abc; b=AbCdEfGh12345678forged"
+)
+
+// withHeader adds values on top of the ones already in headers, as anyone
+// relaying a mail can do.
+func withHeader(headers map[string][]string, key string, values ...string) map[string][]string {
+	headers[key] = append(values, headers[key]...)
+	return headers
+}
+
+func dkimHeaders(authResults string, signatures ...string) map[string][]string {
+	return map[string][]string{
+		fromHeaderKey:          {"News <news@example.com>"},
+		authResultsHeaderKey:   {authResults},
+		dkimSignatureHeaderKey: signatures,
+	}
+}
+
+func TestCheckDKIMAlignment(t *testing.T) {
+	const passing = "mx.google.com; dkim=pass header.i=@example.com header.s=s1 header.b=AbCdEfGh; spf=pass"
+
+	tests := []struct {
+		name    string
+		headers map[string][]string
+		aligned bool
+		reason  string
+	}{
+		{
+			name:    "aligned",
+			headers: dkimHeaders(passing, genuineSignature),
+			aligned: true,
+		},
+		{
+			name:    "identity on a subdomain",
+			headers: dkimHeaders("mx.google.com; dkim=pass header.i=@news.example.com header.b=AbCdEfGh", genuineSignature),
+			aligned: true,
+		},
+		{
+			name:    "forged copy before the genuine one",
+			headers: dkimHeaders(passing, forgedSignature, genuineSignature),
+			reason:  "several DKIM signatures",
+		},
+		{
+			name:    "forged copy after the genuine one",
+			headers: dkimHeaders(passing, genuineSignature, forgedSignature),
+			reason:  "several DKIM signatures",
+		},
+		{
+			name:    "missing header.b",
+			headers: dkimHeaders("mx.google.com; dkim=pass header.i=@example.com", forgedSignature),
+			reason:  "does not identify its signature",
+		},
+		{
+			name:    "not covering List-Unsubscribe",
+			headers: dkimHeaders("mx.google.com; dkim=pass header.i=@example.com header.b=AbCdEfGh12345678f", forgedSignature),
+			reason:  "does not cover every List-Unsubscribe header",
+		},
+		{
+			name: "List-Unsubscribe added on top of the signed one",
+			headers: withHeader(
+				withHeader(dkimHeaders(passing, genuineSignature), unsubscribeHeaderKey, "<https://example.com/unsubscribe>"),
+				unsubscribeHeaderKey, "<https://attacker.com/unsubscribe>",
+			),
+			reason: "does not cover every List-Unsubscribe header",
+		},
+		{
+			name: "List-Unsubscribe-Post added on top of the signed one",
+			headers: withHeader(
+				withHeader(dkimHeaders(passing, genuineSignature), postUnsubscribeHeaderKey, "List-Unsubscribe=One-Click"),
+				postUnsubscribeHeaderKey, "List-Unsubscribe=One-Click",
+			),
+			reason: "does not cover every List-Unsubscribe-Post header",
+		},
+		{
+			name:    "From added on top of the signed one",
+			headers: withHeader(dkimHeaders(passing, genuineSignature), fromHeaderKey, "News <news@example.com>"),
+			reason:  "does not cover every From header",
+		},
+		{
+			name: "every copy signed",
+			headers: withHeader(
+				withHeader(dkimHeaders(passing, twiceSignedSignature), unsubscribeHeaderKey, "<https://example.com/unsubscribe>"),
+				unsubscribeHeaderKey, "<https://example.com/unsubscribe?again>",
+			),
+			aligned: true,
+		},
+		{
+			name:    "failing signature",
+			headers: dkimHeaders("mx.google.com; dkim=fail header.i=@example.com header.b=AbCdEfGh", genuineSignature),
+			reason:  "no passing DKIM signature",
+		},
+		{
+			name:    "untrusted results",
+			headers: dkimHeaders("mx.attacker.com; dkim=pass header.i=@example.com header.b=AbCdEfGh", genuineSignature),
+			reason:  "no trusted Authentication-Results",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckDKIMAlignment(tt.headers)
+			if got.Aligned != tt.aligned {
+				t.Fatalf("aligned = %v (%s), want %v", got.Aligned, got.Reason, tt.aligned)
+			}
+			if !strings.Contains(got.Reason, tt.reason) {
+				t.Errorf("reason = %q, want it to mention %q", got.Reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestNewUnsubscriberAlignment(t *testing.T) {
+	const (
+		passing   = "mx.google.com; dkim=pass header.i=@example.com header.b=AbCdEfGh"
+		unsigned  = "mx.google.com; dkim=fail header.i=@example.com header.b=AbCdEfGh"
+		mailtoSig = "v=1; a=rsa-sha256; d=example.com; s=s1; h=from:subject:list-unsubscribe; bh=abc; b=AbCdEfGh12345678"
+	)
+
+	tests := []struct {
+		name          string
+		headers       map[string][]string
+		method        UnsubscribeMethod
+		blockedMethod UnsubscribeMethod
+	}{
+		{
+			name:    "aligned one-click",
+			headers: withHeader(withHeader(dkimHeaders(passing, genuineSignature), postUnsubscribeHeaderKey, "List-Unsubscribe=One-Click"), unsubscribeHeaderKey, "<https://example.com/u>"),
+			method:  MethodOneClick,
+		},
+		{
+			name:          "unaligned one-click",
+			headers:       withHeader(withHeader(dkimHeaders(unsigned, genuineSignature), postUnsubscribeHeaderKey, "List-Unsubscribe=One-Click"), unsubscribeHeaderKey, "<https://example.com/u>"),
+			method:        MethodLink,
+			blockedMethod: MethodOneClick,
+		},
+		{
+			name:    "aligned mailto",
+			headers: withHeader(dkimHeaders(passing, mailtoSig), unsubscribeHeaderKey, "<mailto:u@example.com>, <https://example.com/u>"),
+			method:  MethodMailto,
+		},
+		{
+			name:          "unaligned mailto",
+			headers:       withHeader(dkimHeaders(unsigned, mailtoSig), unsubscribeHeaderKey, "<mailto:u@attacker.com>, <https://example.com/u>"),
+			method:        MethodLink,
+			blockedMethod: MethodMailto,
+		},
+		{
+			name:    "unaligned mailto without a link",
+			headers: withHeader(dkimHeaders(unsigned, mailtoSig), unsubscribeHeaderKey, "<mailto:u@attacker.com>"),
+			method:  MethodNone,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := MailingList{Unsubscriber: NewUnsubscriber(tt.headers, nopMailer{})}
+			if got := list.UnsubscribeMethod(); got != tt.method {
+				t.Fatalf("method = %s, want %s", got, tt.method)
+			}
+			if got, _ := list.UnsubscribeBlocked(); got != tt.blockedMethod {
+				t.Errorf("blocked method = %s, want %s", got, tt.blockedMethod)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package inbox
+
+import "sort"
+
+// Engagement counts how many mails from a sender were read over some time
+// window, read and unread alike, so lists nobody opens stand out.
+type Engagement struct {
+	Read   int64
+	Unread int64
+}
+
+func (e Engagement) Total() int64 {
+	return e.Read + e.Unread
+}
+
+// OpenRate is the share of mails read, between 0 and 1.
+func (e Engagement) OpenRate() float64 {
+	if e.Total() == 0 {
+		return 0
+	}
+	return float64(e.Read) / float64(e.Total())
+}
+
+// WithEngagement attaches the engagement measured per sender to the lists
+// they belong to.
+func WithEngagement(scores map[string]Engagement) GroupOpt {
+	return func(c *groupConfig) {
+		c.engagement = scores
+	}
+}
+
+// WithEngagement returns the list with the engagement of all its senders
+// added up, left untouched if none of them was measured.
+func (m MailingList) WithEngagement(scores map[string]Engagement) MailingList {
+	var total Engagement
+	var measured bool
+	for _, s := range m.Senders {
+		if e, ok := scores[s]; ok {
+			total.Read += e.Read
+			total.Unread += e.Unread
+			measured = true
+		}
+	}
+	if measured {
+		m.Engagement = &total
+	}
+
+	if len(m.Members) > 0 {
+		members := make([]MailingList, 0, len(m.Members))
+		for _, member := range m.Members {
+			members = append(members, member.WithEngagement(scores))
+		}
+		m.Members = members
+	}
+	return m
+}
+
+// sortAscendingByOpenRate puts the lists read the least first, leaving the
+// ones with no engagement data at the end, ties broken by key.
+func sortAscendingByOpenRate(l []MailingList) {
+	sort.SliceStable(l, func(i, j int) bool {
+		if l[i].Engagement == nil && l[j].Engagement == nil {
+			return l[i].Key < l[j].Key
+		}
+		if l[i].Engagement == nil || l[j].Engagement == nil {
+			return l[i].Engagement != nil
+		}
+		ri, rj := l[i].Engagement.OpenRate(), l[j].Engagement.OpenRate()
+		if ri != rj {
+			return ri < rj
+		}
+		if ti, tj := l[i].Engagement.Total(), l[j].Engagement.Total(); ti != tj {
+			return ti > tj
+		}
+		return l[i].Key < l[j].Key
+	})
+}
@@ -0,0 +1,97 @@
+package inbox
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// GroupKey decides which mails end up in the same MailingList.
+type GroupKey int
+
+const (
+	GroupBySender GroupKey = iota
+	// RFC 2919 List-Id, falling back to the sender for mails without one
+	GroupByListID
+	// registrable domain (eTLD+1) of the sender, so mail.brand.com and
+	// e.brand.com end up together
+	GroupByDomain
+	// Gmail inbox tab, such as Promotions or Social
+	GroupByCategory
+	// original sender of mail relayed by lists like Google Groups, which
+	// GroupBySender lumps together under the list address
+	GroupByAuthor
+)
+
+var groupKeyNames = map[GroupKey]string{
+	GroupBySender:   "sender",
+	GroupByListID:   "list-id",
+	GroupByDomain:   "domain",
+	GroupByCategory: "category",
+	GroupByAuthor:   "author",
+}
+
+func (k GroupKey) String() string {
+	return groupKeyNames[k]
+}
+
+func ParseGroupKey(s string) (GroupKey, error) {
+	for k, name := range groupKeyNames {
+		if strings.EqualFold(s, name) {
+			return k, nil
+		}
+	}
+	return GroupBySender, fmt.Errorf("unknown grouping %q", s)
+}
+
+// Next cycles through the available keys, so the TUI can toggle them.
+func (k GroupKey) Next() GroupKey {
+	return (k + 1) % GroupKey(len(groupKeyNames))
+}
+
+func (k GroupKey) of(rm RawMail) string {
+	switch k {
+	case GroupByListID:
+		if id := rm.ListID(); id != "" {
+			return id
+		}
+	case GroupByDomain:
+		if domain := RegistrableDomain(rm.From); domain != "" {
+			return domain
+		}
+	case GroupByCategory:
+		return rm.Category().String()
+	case GroupByAuthor:
+		if rm.Author != "" {
+			return rm.Author
+		}
+	}
+	return rm.From
+}
+
+// GroupBy buckets mails by the value key returns for them.
+func (rml RawMailList) GroupBy(key func(RawMail) string) map[string][]RawMail {
+	groups := make(map[string][]RawMail)
+	for _, msg := range rml {
+		k := key(msg)
+		groups[k] = append(groups[k], msg)
+	}
+	return groups
+}
+
+// RegistrableDomain returns the eTLD+1 of an address or domain, according to
+// the public suffix list bundled with x/net, or an empty string if it has
+// none.
+func RegistrableDomain(addr string) string {
+	domain := addr
+	if i := strings.LastIndex(addr, "@"); i >= 0 {
+		domain = addr[i+1:]
+	}
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	registrable, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	if err != nil {
+		return ""
+	}
+	return registrable
+}
@@ -0,0 +1,47 @@
+package inbox
+
+import "testing"
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"news@mail.shop.co.uk", "shop.co.uk"},
+		{"deals@news.shop.co.uk", "shop.co.uk"},
+		{"hello@shop.co.uk", "shop.co.uk"},
+		{"info@e.Brand.COM", "brand.com"},
+		{"mail.brand.com.", "brand.com"},
+		{"\"odd@name\"@brand.com", "brand.com"},
+		{"someone@co.uk", ""},
+		{"someone@localhost", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := RegistrableDomain(tt.in); got != tt.want {
+				t.Errorf("RegistrableDomain(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupByDomain(t *testing.T) {
+	tests := []struct {
+		from string
+		want string
+	}{
+		{"news@mail.shop.co.uk", "shop.co.uk"},
+		{"deals@news.shop.co.uk", "shop.co.uk"},
+		{"alerts@e.brand.com", "brand.com"},
+		// no registrable domain, left alone
+		{"root@localhost", "root@localhost"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from, func(t *testing.T) {
+			if got := GroupByDomain.of(RawMail{From: tt.from}); got != tt.want {
+				t.Errorf("grouped under %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package inbox
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// wordDecoder decodes RFC 2047 encoded-words in any charset known to browsers,
+// not only the utf-8 and latin-1 ones net/mail supports on its own.
+var wordDecoder = &mime.WordDecoder{
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		enc, err := htmlindex.Get(charset)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported charset %q: %w", charset, err)
+		}
+		return enc.NewDecoder().Reader(input), nil
+	},
+}
+
+var addressParser = &mail.AddressParser{WordDecoder: wordDecoder}
+
+var (
+	dateCommentRe = regexp.MustCompile(`\([^)]*\)`)
+
+	// layouts seen in the wild on top of what net/mail accepts, mostly from
+	// broken mailers: missing seconds or weekday, two digit years, named
+	// zones, long names or no zone at all
+	dateLayouts = []string{
+		"Mon, 2 Jan 2006 15:04:05 MST",
+		"Mon, 2 Jan 2006 15:04 -0700",
+		"Mon, 2 Jan 2006 15:04 MST",
+		"Mon, 2 Jan 06 15:04:05 -0700",
+		"Mon, 2 Jan 06 15:04:05 MST",
+		"2 Jan 2006 15:04:05 -0700",
+		"2 Jan 2006 15:04:05 MST",
+		"2 Jan 06 15:04:05 -0700",
+		"Mon, 2 January 2006 15:04:05 -0700",
+		"Monday, 2 Jan 2006 15:04:05 -0700",
+		"Monday, 2 January 2006 15:04:05 -0700",
+		"Mon, 2 Jan 2006 15:04:05",
+		"Mon, 2 Jan 2006 15:04:05 -07:00",
+		"Mon, 2-Jan-2006 15:04:05 -0700",
+		"Mon Jan 2 15:04:05 2006",
+		"Mon Jan 2 15:04:05 MST 2006",
+		"Mon Jan 2 15:04:05 -0700 2006",
+		time.RFC3339,
+		"2006-01-02 15:04:05 -0700",
+		"2006-01-02 15:04:05",
+	}
+)
+
+// ParseDate reads a Date header, tolerating the many ways it is malformed in
+// practice. Comments and surrounding noise are dropped before trying the RFC
+// 5322 format and then a list of known deviations.
+func ParseDate(s string) (time.Time, bool) {
+	s = strings.Join(strings.Fields(dateCommentRe.ReplaceAllString(s, " ")), " ")
+	s = strings.TrimRight(s, ".,; ")
+	if s == "" {
+		return time.Time{}, false
+	}
+
+	// a zero date tells nothing more than a missing one
+	if t, err := mail.ParseDate(s); err == nil {
+		return t, !t.IsZero()
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, !t.IsZero()
+		}
+	}
+	return time.Time{}, false
+}
+
+// ParseAddresses reads an address list header, keeping every address it can
+// make out when the list as a whole is malformed. It tells whether every
+// value parsed cleanly.
+func ParseAddresses(values []string) ([]*mail.Address, bool) {
+	var addrs []*mail.Address
+	clean := true
+	for _, v := range values {
+		if list, err := addressParser.ParseList(v); err == nil {
+			addrs = append(addrs, list...)
+			continue
+		}
+		clean = false
+		for _, part := range strings.Split(v, ",") {
+			if a, _ := ParseAddress(part); a != nil {
+				addrs = append(addrs, a)
+			}
+		}
+	}
+	return addrs, clean
+}
+
+// ParseAddress reads a single address, falling back to whatever looks like
+// one when net/mail rejects it, as broken and obsolete mailers produce things
+// like unquoted dots or commas in names, bare addresses followed by a comment,
+// or several nested angle brackets. It tells whether the value parsed
+// cleanly, and returns nil only when nothing resembling an address is found.
+func ParseAddress(s string) (*mail.Address, bool) {
+	if a, err := addressParser.Parse(s); err == nil {
+		return a, true
+	}
+
+	s = strings.TrimSpace(s)
+	if start := strings.LastIndex(s, "<"); start >= 0 {
+		if end := strings.Index(s[start:], ">"); end > 0 {
+			addr := strings.TrimSpace(s[start+1 : start+end])
+			if strings.Contains(addr, "@") {
+				return &mail.Address{Name: lenientName(s[:start]), Address: addr}, false
+			}
+		}
+	}
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`<>()"',;`, r)
+	}) {
+		if strings.Count(f, "@") == 1 && !strings.HasPrefix(f, "@") && !strings.HasSuffix(f, "@") {
+			return &mail.Address{Address: f}, false
+		}
+	}
+	return nil, false
+}
+
+func lenientName(s string) string {
+	s = strings.Trim(strings.TrimSpace(s), `"'<> `)
+	return strings.TrimSpace(DecodeHeader(s))
+}
+
+// DecodeHeader decodes RFC 2047 encoded-words, returning the raw value when
+// they cannot be decoded.
+func DecodeHeader(s string) string {
+	decoded, _ := decodeHeader(s)
+	return decoded
+}
+
+// decodeHeader also tells whether every encoded-word could be decoded.
+// Unfolded line breaks are collapsed, as some mailers leave them in.
+func decodeHeader(s string) (string, bool) {
+	s = strings.Join(strings.Fields(s), " ")
+	decoded, err := wordDecoder.DecodeHeader(s)
+	if err != nil {
+		return s, false
+	}
+	return decoded, true
+}
+
+// Sanitize drops control characters but line breaks and tabs from text sent
+// by senders, which could otherwise move the cursor, retitle the terminal or
+// write to the clipboard through escape sequences.
+func Sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if r < 0x20 || r == 0x7f || (r >= 0x80 && r <= 0x9f) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func addressesOf(addrs []*mail.Address) []string {
+	out := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		out = append(out, strings.ToLower(a.Address))
+	}
+	return out
+}
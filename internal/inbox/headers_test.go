@@ -0,0 +1,131 @@
+package inbox
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+// headers seen in the wild that net/mail rejects
+var brokenAddresses = []string{
+	`John Doe <john@example.com>`,
+	`John Q. Public <john@example.com>`,
+	`Doe, John <john@example.com>`,
+	`john@example.com (John Doe)`,
+	`"News" <<news@example.com>>`,
+	`<news@example.com`,
+	`"Unterminated <news@example.com>`,
+	`=?utf-8?q?Caf=C3=A9?= <cafe@example.com>`,
+	`=?x-unknown?q?foo?= <foo@example.com>`,
+	`'Single quoted' <news@example.com>`,
+	`news@example.com;`,
+	`@example.com`,
+	`not an address`,
+	``,
+	`<>`,
+}
+
+func FuzzParseAddress(f *testing.F) {
+	for _, s := range brokenAddresses {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		a, _ := ParseAddress(s)
+		if a != nil && a.Address == "" {
+			t.Errorf("ParseAddress(%q) returned an empty address", s)
+		}
+	})
+}
+
+func FuzzParseAddresses(f *testing.F) {
+	f.Add(strings.Join(brokenAddresses[:4], ", "))
+	f.Add(`a@example.com, Doe, John <john@example.com>, broken`)
+	f.Add(`undisclosed-recipients:;`)
+	for _, s := range brokenAddresses {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		addrs, _ := ParseAddresses([]string{s})
+		for _, a := range addrs {
+			if a == nil || a.Address == "" {
+				t.Errorf("ParseAddresses(%q) returned an empty address", s)
+			}
+		}
+	})
+}
+
+func FuzzParseDate(f *testing.F) {
+	for _, s := range []string{
+		"Mon, 2 Jan 2006 15:04:05 -0700",
+		"Mon, 2 Jan 2006 15:04:05 -0700 (PST)",
+		"Mon, 2 Jan 2006 15:04:05 PST",
+		"Mon, 2 Jan 2006 15:04 -0700",
+		"2 Jan 06 15:04:05 -0700",
+		"Monday, 2 January 2006 15:04:05 -0700",
+		"Mon, 2 Jan 2006 15:04:05",
+		"Mon, 2-Jan-2006 15:04:05 -0700",
+		"Mon Jan 2 15:04:05 2006",
+		"2006-01-02T15:04:05Z",
+		"0001-01-01T00:00:00Z",
+		"Mon, 2 Jan 2006 15:04:05 -0700.",
+		"(comment only)",
+		"",
+		"not a date",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		if d, ok := ParseDate(s); ok && d.IsZero() {
+			t.Errorf("ParseDate(%q) succeeded with a zero time", s)
+		}
+	})
+}
+
+func TestWithSender(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		from    string
+	}{
+		{"clean", map[string]string{"From": "Acme <News@Acme.com>"}, "news@acme.com"},
+		{"broken", map[string]string{"From": "Doe, John <john@example.com>"}, "john@example.com"},
+		{"no address", map[string]string{"From": "  Acme   Newsletter "}, "Acme Newsletter"},
+		{"blank", map[string]string{"From": "   "}, unknownSender},
+		{"sender fallback", map[string]string{"Sender": "list@example.com"}, "list@example.com"},
+		{"missing", nil, unknownSender},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &gmail.Message{Payload: &gmail.MessagePart{}}
+			for k, v := range tt.headers {
+				msg.Payload.Headers = append(msg.Payload.Headers, &gmail.MessagePartHeader{Name: k, Value: v})
+			}
+			var rm RawMail
+			if err := WithSender(msg)(&rm); err != nil {
+				t.Fatal(err)
+			}
+			if rm.From != tt.from {
+				t.Errorf("From = %q, want %q", rm.From, tt.from)
+			}
+			if key := GroupBySender.of(rm); key == "" {
+				t.Error("grouped under an empty key")
+			}
+		})
+	}
+}
+
+func TestTitleSanitizesName(t *testing.T) {
+	a, _ := ParseAddress("=?utf-8?q?Evil=1B]0;x=07?= <evil@example.com>")
+	if a == nil || !strings.ContainsRune(a.Name, 0x1b) {
+		t.Fatalf("ParseAddress did not decode the escape sequence: %+v", a)
+	}
+
+	list := MailingList{Key: a.Address, From: a.Address, Name: a.Name, Recipients: []string{"a@example.com", "b\x1b[2J@example.com"}}
+	if title := list.Title(); strings.ContainsAny(title, "\x1b\x07") {
+		t.Errorf("Title() = %q keeps control characters", title)
+	}
+	if desc := list.Description(); strings.ContainsAny(desc, "\x1b\x07") {
+		t.Errorf("Description() = %q keeps control characters", desc)
+	}
+}
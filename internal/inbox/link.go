@@ -0,0 +1,112 @@
+package inbox
+
+import (
+	"context"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sverdejot/geemail/internal/browser"
+)
+
+// only the head of the landing page is needed to find its title
+const maxLandingPageSize = 64 << 10
+
+var landingTitleRe = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// linkUnsubscriber handles senders advertising an https List-Unsubscribe URL
+// without RFC 8058 one-click support. Visiting the link may be all it takes,
+// but it may as well land on a form, hence it must only run after the user
+// confirms it.
+type linkUnsubscriber struct {
+	attemptLog
+	target *url.URL
+	// the unattended method refused in favor of this link, if one was
+	// offered, and why
+	blockedMethod UnsubscribeMethod
+	blocked       string
+}
+
+func NewLinkUnsubscriber(headers map[string][]string) *linkUnsubscriber {
+	for _, u := range unsubscribeTargets(headers) {
+		if u.Scheme == "https" {
+			return &linkUnsubscriber{target: u}
+		}
+	}
+	return nil
+}
+
+func (u *linkUnsubscriber) Method() UnsubscribeMethod {
+	return MethodLink
+}
+
+func (u *linkUnsubscriber) Target() string {
+	return u.target.String()
+}
+
+// Blocked returns the one-click or mailto unsubscribe the sender offered but
+// could not be trusted and why, or an empty reason if none was offered.
+func (u *linkUnsubscriber) Blocked() (UnsubscribeMethod, string) {
+	return u.blockedMethod, u.blocked
+}
+
+// Do visits the unsubscribe link and records the landing page title, or its
+// status when it has none.
+func (u *linkUnsubscriber) Do(ctx context.Context) error {
+	if u == nil {
+		return ErrNoUnsubscriber
+	}
+
+	attempt := Attempt{
+		Method: MethodLink,
+		Target: u.target.String(),
+		At:     time.Now(),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.target.String(), nil)
+	if err != nil {
+		attempt.Err = err
+		return u.record(attempt)
+	}
+
+	attempt.Status, attempt.Err = doUnsubscribeRequest(req, func(resp *http.Response) error {
+		attempt.Detail = resp.Status
+		page, err := io.ReadAll(io.LimitReader(resp.Body, maxLandingPageSize))
+		if err != nil {
+			// the request itself went through, the title is a nicety
+			return nil
+		}
+		if title := landingTitle(page); title != "" {
+			attempt.Detail = title
+		}
+		return nil
+	})
+	return u.record(attempt)
+}
+
+// Open lets the user complete the unsubscription in their browser.
+func (u *linkUnsubscriber) Open() error {
+	if u == nil {
+		return ErrNoUnsubscriber
+	}
+
+	return u.record(Attempt{
+		Method: MethodLink,
+		Target: u.target.String(),
+		At:     time.Now(),
+		Detail: "opened in browser",
+		Err:    browser.Open(u.target.String()),
+	})
+}
+
+func landingTitle(page []byte) string {
+	m := landingTitleRe.FindSubmatch(page)
+	if m == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(string(m[1]))), " ")
+}
@@ -2,20 +2,59 @@ package inbox
 
 import (
 	"fmt"
-	"net/mail"
+	"maps"
+	"slices"
 	"strings"
+	"time"
 
 	"google.golang.org/api/gmail/v1"
 )
 
+const (
+	listIDHeaderKey  = "list-id"
+	subjectHeaderKey = "subject"
+	dateHeaderKey    = "date"
+
+	// sender of mails telling nothing about who sent them, so they are
+	// grouped under a name rather than an empty one
+	unknownSender = "(unknown sender)"
+)
+
 type RawMailList []RawMail
 
 type RawMail struct {
-	ID               string
-	From             string
-	To               []string
-	Subject, Snippet string
-	Headers          map[string][]string
+	ID   string
+	From string
+	// display name of the sender, decoded
+	Name string
+	// who wrote the mail when a list or forwarder relayed it under its own
+	// From, the sender otherwise
+	Author, AuthorName string
+	To                 []string
+	Cc                 []string
+	DeliveredTo        []string
+	Subject, Snippet   string
+	Headers            map[string][]string
+	// when the sender claims to have sent the mail, Received if it did not
+	// say or the header cannot be parsed
+	Date time.Time
+	// when Gmail received the mail
+	Received time.Time
+	// Gmail label IDs, both system and user ones
+	Labels []string
+	// estimated size in bytes, attachments included
+	Size           int64
+	HasAttachments bool
+	// fields that were missing or malformed and had to be guessed or left
+	// empty, see ParseWarnings
+	Warnings []string
+}
+
+// warn notes that field could not be read as expected, once per mail.
+func (rm *RawMail) warn(field string) {
+	if !slices.Contains(rm.Warnings, field) {
+		rm.Warnings = append(rm.Warnings, field)
+	}
 }
 
 type RawMailOpt func(*RawMail) error
@@ -37,43 +76,128 @@ func WithID(msg *gmail.Message) RawMailOpt {
 	}
 }
 
-func WithHeaders(msg *gmail.Message) (fn RawMailOpt) {
-	hs := make(map[string][]string)
-	for _, h := range msg.Payload.Headers {
-		name := strings.ToLower(h.Name)
-		hs[name] = append(hs[name], h.Value)
+func WithDate(msg *gmail.Message) RawMailOpt {
+	received := time.UnixMilli(msg.InternalDate)
+	date, malformed := received, false
+	if vals := payloadHeaders(msg)[dateHeaderKey]; len(vals) > 0 {
+		if t, ok := ParseDate(vals[0]); ok {
+			date = t
+		} else {
+			malformed = true
+		}
 	}
 	return func(rm *RawMail) error {
-		rm.Headers = hs
+		rm.Received = received
+		rm.Date = date
+		if malformed {
+			rm.warn(dateHeaderKey)
+		}
 		return nil
 	}
 }
 
-func WithSubject(msg *gmail.Message) (fn RawMailOpt) {
-	if msg == nil || msg.Payload == nil {
-		return func(rm *RawMail) error {
-			return fmt.Errorf("malformed mail: empty or nil message: %v", msg)
+func WithRecipients(msg *gmail.Message) RawMailOpt {
+	hs := payloadHeaders(msg)
+	return func(rm *RawMail) error {
+		for _, f := range []struct {
+			key  string
+			dest *[]string
+		}{
+			{"to", &rm.To},
+			{"cc", &rm.Cc},
+			{"delivered-to", &rm.DeliveredTo},
+		} {
+			addrs, ok := ParseAddresses(hs[f.key])
+			if !ok {
+				rm.warn(f.key)
+			}
+			*f.dest = addressesOf(addrs)
+		}
+		return nil
+	}
+}
+
+func WithLabels(msg *gmail.Message) RawMailOpt {
+	return func(rm *RawMail) error {
+		rm.Labels = msg.LabelIds
+		return nil
+	}
+}
+
+func WithSize(msg *gmail.Message) RawMailOpt {
+	return func(rm *RawMail) error {
+		rm.Size = msg.SizeEstimate
+		return nil
+	}
+}
+
+func WithAttachments(msg *gmail.Message) RawMailOpt {
+	return func(rm *RawMail) error {
+		rm.HasAttachments = hasAttachments(msg.Payload)
+		return nil
+	}
+}
+
+func hasAttachments(part *gmail.MessagePart) bool {
+	if part == nil {
+		return false
+	}
+	if part.Filename != "" || (part.Body != nil && part.Body.AttachmentId != "") {
+		return true
+	}
+	for _, p := range part.Parts {
+		if hasAttachments(p) {
+			return true
 		}
 	}
+	return false
+}
 
+// payloadHeaders indexes the headers of msg by lowercase name, empty when
+// the payload is missing.
+func payloadHeaders(msg *gmail.Message) map[string][]string {
+	hs := make(map[string][]string)
+	if msg == nil || msg.Payload == nil {
+		return hs
+	}
 	for _, h := range msg.Payload.Headers {
-		if h.Name == "Subject" {
-			return func(c *RawMail) error {
-				c.Subject = h.Value
-				return nil
-			}
+		name := strings.ToLower(h.Name)
+		hs[name] = append(hs[name], h.Value)
+	}
+	return hs
+}
+
+func WithHeaders(msg *gmail.Message) (fn RawMailOpt) {
+	hs := payloadHeaders(msg)
+	return func(rm *RawMail) error {
+		rm.Headers = hs
+		if msg == nil || msg.Payload == nil {
+			rm.warn("payload")
 		}
+		return nil
 	}
+}
 
+// WithSubject decodes the subject, which is left empty when there is none.
+func WithSubject(msg *gmail.Message) (fn RawMailOpt) {
+	vals := payloadHeaders(msg)[subjectHeaderKey]
 	return func(rm *RawMail) error {
-		return fmt.Errorf("malformed mail: no subject")
+		if len(vals) == 0 {
+			return nil
+		}
+		subject, ok := decodeHeader(vals[0])
+		if !ok {
+			rm.warn(subjectHeaderKey)
+		}
+		rm.Subject = subject
+		return nil
 	}
 }
 
 func WithSnippet(msg *gmail.Message) (fn RawMailOpt) {
-	if msg == nil || msg.Payload == nil {
+	if msg == nil {
 		return func(rm *RawMail) error {
-			return fmt.Errorf("malformed mail: emtpy message")
+			return fmt.Errorf("malformed mail: nil message")
 		}
 	}
 	return func(rm *RawMail) error {
@@ -82,31 +206,95 @@ func WithSnippet(msg *gmail.Message) (fn RawMailOpt) {
 	}
 }
 
+// WithSender reads the From address and display name. When From cannot be
+// parsed, whatever looks like an address in it is kept, or the raw value if
+// nothing does, so mail from broken senders still shows up. Mails without
+// From are attributed to their Sender or Return-Path, and to an unknown
+// sender without any of them.
 func WithSender(msg *gmail.Message) RawMailOpt {
-	if msg == nil || msg.Payload == nil {
-		return func(rm *RawMail) error {
-			return fmt.Errorf("malformed mail: emtpy message")
+	hs := payloadHeaders(msg)
+	return func(rm *RawMail) error {
+		from := hs[fromHeaderKey]
+		if len(from) == 0 {
+			rm.warn(fromHeaderKey)
+			from = append(hs["sender"], hs["return-path"]...)
 		}
+		if len(from) == 0 {
+			rm.From = unknownSender
+			return nil
+		}
+
+		addr, ok := ParseAddress(from[0])
+		if !ok {
+			rm.warn(fromHeaderKey)
+		}
+		if addr == nil {
+			rm.From = firstNonEmpty(strings.Join(strings.Fields(from[0]), " "), unknownSender)
+			return nil
+		}
+		// lowercased like the author, so mail from one sender is not split
+		// into several groups over its case
+		rm.From = strings.ToLower(addr.Address)
+		rm.Name = addr.Name
+		return nil
 	}
+}
 
-	for _, h := range msg.Payload.Headers {
-		if h.Name == "From" {
-			addr, err := mail.ParseAddress(h.Value)
-			if err != nil {
-				return func(rm *RawMail) error {
-					return fmt.Errorf("malformed mail: unparseable sender")
-				}
-			}
-			return func(rm *RawMail) error {
-				rm.From = addr.Address
-				return nil
-			}
+// ListID returns the RFC 2919 list identifier, without the surrounding angle
+// brackets or phrase, or an empty string if the mail has none.
+func (rm RawMail) ListID() string {
+	vals := rm.Headers[listIDHeaderKey]
+	if len(vals) == 0 {
+		return ""
+	}
+	id := vals[0]
+	if start := strings.LastIndex(id, "<"); start >= 0 {
+		if end := strings.Index(id[start:], ">"); end > 0 {
+			id = id[start+1 : start+end]
 		}
 	}
+	return strings.ToLower(strings.TrimSpace(id))
+}
 
-	return func(rm *RawMail) error {
-		return fmt.Errorf("malformed mail: no sender found")
+// Recipients returns the addresses of ours the mail was delivered to,
+// trusting Delivered-To over the headers the sender wrote.
+func (rm RawMail) Recipients() []string {
+	if len(rm.DeliveredTo) > 0 {
+		return rm.DeliveredTo
+	}
+	return append(append([]string{}, rm.To...), rm.Cc...)
+}
+
+// ParseWarnings counts, per header, how many mails it had to be guessed or
+// left empty for.
+type ParseWarnings map[string]int
+
+func CountParseWarnings(l RawMailList) ParseWarnings {
+	w := make(ParseWarnings)
+	for _, rm := range l {
+		for _, field := range rm.Warnings {
+			w[field]++
+		}
+	}
+	return w
+}
+
+// Total is how many fields could not be read, summed over every mail.
+func (w ParseWarnings) Total() int {
+	var n int
+	for _, c := range w {
+		n += c
 	}
+	return n
+}
+
+// String renders the counts sorted by field, e.g. "date:2 from:5".
+func (w ParseWarnings) String() string {
+	parts := make([]string, 0, len(w))
+	for _, field := range slices.Sorted(maps.Keys(w)) {
+		parts = append(parts, fmt.Sprintf("%s:%d", field, w[field]))
+	}
+	return strings.Join(parts, " ")
 }
 
 func (rm RawMail) FilterValue() string {
@@ -126,18 +314,7 @@ func (rm RawMail) String() string {
 }
 
 func (rml RawMailList) GroupBySender() map[string][]RawMail {
-	senders := make(map[string][]RawMail)
-
-	for _, msg := range rml {
-		if _, ok := senders[msg.From]; ok {
-			senders[msg.From] = append(senders[msg.From], msg)
-			continue
-		}
-		senders[msg.From] = make([]RawMail, 0)
-		senders[msg.From] = append(senders[msg.From], msg)
-	}
-
-	return senders
+	return rml.GroupBy(GroupBySender.of)
 }
 
 func (rml RawMailList) String() string {
@@ -1,13 +1,19 @@
 package inbox
 
 import (
+	"cmp"
 	"context"
 	"fmt"
-	"sort"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 )
 
+// RFC 8058 gives senders up to two days to process an unsubscribe
+const UnsubscribeGracePeriod = 48 * time.Hour
+
 var (
 	unsubscribeListElemstyle = lipgloss.
 		NewStyle().
@@ -15,50 +21,248 @@ var (
 )
 
 type MailingList struct {
-	From              string
+	// value shared by every mail in the list, as chosen by the GroupKey
+	Key string
+	// first sender found in the list, the only one when grouping by sender
+	From string
+	// display name of From, empty when it never gave one
+	Name    string
+	Senders []string
+	ListID  string
+	// per sender breakdown, only set when grouping by domain
+	Members []MailingList
+	// per subject template breakdown, unset when every mail follows the
+	// same template
+	Templates         []MailingList
 	TotalUnreads      int
 	UnreadMessagesIDs []string
-	Unsubscriber      *unsubscriber
+	// every mail in the list, so actions can target part of it
+	Messages []MessageRef
+	// how old the mails in the list are, counted per AgeBuckets
+	Ages         AgeHistogram
+	Unsubscriber Unsubscriber
+	// storage used by every mail in the list, in bytes
+	TotalBytes int64
+	// how many of the mails carry attachments
+	WithAttachments int
+	// read history of the senders, nil unless it was measured
+	Engagement *Engagement
+	// what kind of sender this most likely is
+	Classification Classification
+	// when the oldest and most recent mails in the list were received
+	FirstSeen, LastSeen time.Time
+	// our addresses the list mails to, telling apart aliases
+	Recipients []string
+	// when the user last unsubscribed from the list, zero if never
+	UnsubscribedAt time.Time
+}
+
+type GroupOpt func(*groupConfig)
+
+type groupConfig struct {
+	mailer Mailer
+	key    GroupKey
+	order  SortOrder
+	view   ViewMode
+	// DefaultClassifier when nil
+	classifier *Classifier
+	// engagement measured per sender, if any
+	engagement map[string]Engagement
+	// every one of them must hold for a mail to be grouped
+	filters []func(RawMail) bool
+	// set when building members or templates, which are not split any
+	// further
+	subgroup bool
+	// list the templates are split from, whose unsubscriber and
+	// classification they share
+	parent *MailingList
 }
 
-func GetMailingList(l RawMailList) []MailingList {
+// WithMailer enables mailto unsubscribe targets, sending the requests
+// through the given mailer.
+func WithMailer(m Mailer) GroupOpt {
+	return func(c *groupConfig) {
+		c.mailer = m
+	}
+}
+
+// WithGroupKey sets how mails are grouped, by sender if not given.
+func WithGroupKey(k GroupKey) GroupOpt {
+	return func(c *groupConfig) {
+		c.key = k
+	}
+}
+
+func GetMailingList(l RawMailList, opts ...GroupOpt) []MailingList {
+	var cfg groupConfig
+	for _, fn := range opts {
+		fn(&cfg)
+	}
+	return cfg.group(l)
+}
+
+func (cfg groupConfig) group(l RawMailList) []MailingList {
 	lists := make([]MailingList, 0)
-	mails := l.GroupBySender()
-
-	var total int
-	for s, sm := range mails {
-		ids := make([]string, 0, len(sm))
-		var unsubscriber *unsubscriber
-		for _, rm := range sm {
-			if isMailingList(rm) {
-				total += 1
-				ids = append(ids, rm.ID)
-				if unsubscriber == nil {
-					unsubscriber = NewOneClickUnsubscriber(rm.Headers)
-				}
+	for k, sm := range cfg.filter(l).GroupBy(cfg.key.of) {
+		if list, ok := newMailingList(k, sm, cfg); ok {
+			lists = append(lists, list)
+		}
+	}
+	SortMailingLists(lists, cfg.order)
+	return lists
+}
+
+func (cfg groupConfig) filter(l RawMailList) RawMailList {
+	if len(cfg.filters) == 0 {
+		return l
+	}
+	kept := make(RawMailList, 0, len(l))
+	for _, rm := range l {
+		if !slices.ContainsFunc(cfg.filters, func(keep func(RawMail) bool) bool { return !keep(rm) }) {
+			kept = append(kept, rm)
+		}
+	}
+	return kept
+}
+
+// newMailingList builds the list for every mail sharing the same key, if any
+// of them is worth showing in the current view.
+func newMailingList(key string, mails []RawMail, cfg groupConfig) (MailingList, bool) {
+	list := MailingList{
+		Key:               key,
+		UnreadMessagesIDs: make([]string, 0, len(mails)),
+	}
+	classifier := cfg.classifier
+	if classifier == nil {
+		classifier = DefaultClassifier
+	}
+
+	listMails := make(RawMailList, 0, len(mails))
+	var listIDs []string
+	for _, rm := range mails {
+		// mails of a parent list already made it into the view
+		if cfg.parent == nil && !cfg.view.includes(rm, classifier) {
+			continue
+		}
+		listMails = append(listMails, rm)
+		list.TotalUnreads += 1
+		list.UnreadMessagesIDs = append(list.UnreadMessagesIDs, rm.ID)
+		list.Messages = append(list.Messages, MessageRef{
+			ID:             rm.ID,
+			Received:       rm.Received,
+			Size:           rm.Size,
+			HasAttachments: rm.HasAttachments,
+			Labels:         rm.Labels,
+		})
+		list.TotalBytes += rm.Size
+		if rm.HasAttachments {
+			list.WithAttachments++
+		}
+		if !slices.Contains(list.Senders, rm.From) {
+			list.Senders = append(list.Senders, rm.From)
+		}
+		if list.Unsubscriber == nil && cfg.parent == nil && isMailingList(rm) {
+			list.Unsubscriber = NewUnsubscriber(rm.Headers, cfg.mailer)
+		}
+		if list.ListID == "" {
+			list.ListID = rm.ListID()
+		}
+		if !slices.Contains(listIDs, rm.ListID()) {
+			listIDs = append(listIDs, rm.ListID())
+		}
+		if rm.Received.After(list.LastSeen) {
+			list.LastSeen = rm.Received
+		}
+		if list.FirstSeen.IsZero() || rm.Received.Before(list.FirstSeen) {
+			list.FirstSeen = rm.Received
+		}
+		for _, r := range rm.Recipients() {
+			if !slices.Contains(list.Recipients, r) {
+				list.Recipients = append(list.Recipients, r)
 			}
 		}
-		if total > 0 {
-			lists = append(lists, MailingList{
-				From:              s,
-				TotalUnreads:      total,
-				UnreadMessagesIDs: ids,
-				Unsubscriber:      unsubscriber,
-			})
+	}
+	// sharing them rather than building them again spares checking DKIM and
+	// classifying once more, and keeps a single log of unsubscribe attempts
+	if cfg.parent != nil {
+		list.Unsubscriber = cfg.parent.Unsubscriber
+		list.Classification = cfg.parent.Classification
+	}
+	if list.TotalUnreads == 0 || !cfg.view.keeps(list) {
+		return MailingList{}, false
+	}
+	list.From = list.Senders[0]
+	// unsubscribing goes with deleting the whole group, which would take mail
+	// from lists the user never left when it mixes several of them
+	if len(list.Senders) > 1 && (len(listIDs) > 1 || listIDs[0] == "") {
+		list.Unsubscriber = nil
+	}
+	list.Ages = newAgeHistogram(list.Messages, time.Now())
+	for _, rm := range listMails {
+		if rm.From == list.From && rm.Name != "" {
+			list.Name = rm.Name
+			break
 		}
-		total = 0
 	}
-	sortAscendingByTotalUnreads(lists)
-	return lists
+
+	if cfg.parent == nil {
+		list.Classification = classifier.classifyGroup(listMails)
+	}
+
+	if cfg.engagement != nil {
+		list = list.WithEngagement(cfg.engagement)
+	}
+
+	// domains gather many senders, keep them apart so actions can target a
+	// single one
+	if cfg.key == GroupByDomain && cfg.parent == nil {
+		memberCfg := cfg
+		memberCfg.key = GroupBySender
+		memberCfg.filters = nil
+		memberCfg.subgroup = true
+		list.Members = memberCfg.group(listMails)
+	}
+	if !cfg.subgroup {
+		list.Templates = splitTemplates(listMails, cfg, list)
+	}
+	return list, true
 }
 
 func (m MailingList) Unsubscribe(ctx context.Context) error {
+	if m.Unsubscriber == nil {
+		return ErrNoUnsubscriber
+	}
 	return m.Unsubscriber.Do(ctx)
 }
 
+// StillMailing tells whether the sender kept mailing once the unsubscribe
+// grace period was over, and for how many days after unsubscribing it did.
+func (m MailingList) StillMailing() (int, bool) {
+	if m.UnsubscribedAt.IsZero() || m.LastSeen.Before(m.UnsubscribedAt.Add(UnsubscribeGracePeriod)) {
+		return 0, false
+	}
+	return int(m.LastSeen.Sub(m.UnsubscribedAt).Hours() / 24), true
+}
+
+// SplitStillMailing separates the lists that ignored a previous unsubscribe
+// from the rest, keeping their order.
+func SplitStillMailing(lists []MailingList) (regular, ignoring []MailingList) {
+	for _, l := range lists {
+		if _, ok := l.StillMailing(); ok {
+			ignoring = append(ignoring, l)
+			continue
+		}
+		regular = append(regular, l)
+	}
+	return regular, ignoring
+}
+
+// sortAscendingByTotalUnreads puts the lists with the most unread mails
+// first. Lists come out of a map, so ties are broken by key for them to keep
+// their place across rebuilds.
 func sortAscendingByTotalUnreads(l []MailingList) {
-	sort.Slice(l, func(i, j int) bool {
-		return l[i].TotalUnreads > l[j].TotalUnreads
+	slices.SortStableFunc(l, func(a, b MailingList) int {
+		return cmp.Or(cmp.Compare(b.TotalUnreads, a.TotalUnreads), cmp.Compare(a.Key, b.Key))
 	})
 }
 
@@ -68,20 +272,106 @@ func isMailingList(rm RawMail) bool {
 }
 
 func (rm MailingList) FilterValue() string {
-	return rm.From
+	return rm.Key
 }
 
 func (rm MailingList) Title() string {
+	title := rm.Key
+	if rm.Key == rm.From && rm.Name != "" {
+		title = fmt.Sprintf("%s <%s>", rm.Name, rm.From)
+	} else if rm.Key != rm.From {
+		title = fmt.Sprintf("%s (%s)", rm.Key, rm.sendersSummary())
+	}
+	title = rm.UnsubscribeMethod().Icon() + " " + Sanitize(title)
 	if rm.Unsubscriber != nil {
-		return unsubscribeListElemstyle.Render(rm.From)
+		return unsubscribeListElemstyle.Render(title)
+	}
+	return title
+}
+
+func (rm MailingList) sendersSummary() string {
+	if len(rm.Senders) > 1 {
+		return fmt.Sprintf("%s and %d more", rm.From, len(rm.Senders)-1)
 	}
 	return rm.From
 }
 
+// Description sums the list up, sanitized as recipients come from the
+// sender.
 func (rm MailingList) Description() string {
-	return fmt.Sprintf("%d unread", rm.TotalUnreads)
+	return Sanitize(rm.description())
+}
+
+func (rm MailingList) description() string {
+	desc := fmt.Sprintf("%d unread · %s", rm.TotalUnreads, FormatBytes(rm.TotalBytes))
+	if c := rm.Classification; c.Kind != KindUnknown {
+		desc = fmt.Sprintf("%s · %s %.0f%%", desc, c.Kind, c.Confidence*100)
+	}
+	if rm.Engagement != nil && rm.Engagement.Total() > 0 {
+		desc = fmt.Sprintf("%s · opened %.0f%%", desc, rm.Engagement.OpenRate()*100)
+	}
+	if len(rm.Templates) > 1 {
+		desc = fmt.Sprintf("%s · %d subjects", desc, len(rm.Templates))
+	}
+	if ages := rm.Ages.String(); ages != "" {
+		desc = fmt.Sprintf("%s · age %s", desc, ages)
+	}
+	if !rm.FirstSeen.IsZero() {
+		desc = fmt.Sprintf("%s · first %s · last %s", desc, rm.FirstSeen.Format("2 Jan 2006"), rm.LastSeen.Format("2 Jan 2006"))
+	}
+	// only worth showing when the list reaches us through an alias
+	if len(rm.Recipients) > 1 {
+		desc = fmt.Sprintf("%s · to %s", desc, strings.Join(rm.Recipients, ", "))
+	}
+	if days, ok := rm.StillMailing(); ok {
+		return fmt.Sprintf("%s · still mailing %d days after unsubscribe", desc, days)
+	}
+	if method, reason := rm.UnsubscribeBlocked(); reason != "" {
+		return fmt.Sprintf("%s · %s (%s blocked)", desc, rm.UnsubscribeMethod(), method)
+	}
+	return fmt.Sprintf("%s · %s", desc, rm.UnsubscribeMethod())
+}
+
+// UnsubscribeBlocked returns the one-click or mailto unsubscribe offered by
+// the sender that was not trusted and why, an empty reason if there was none
+// or it was honored.
+func (rm MailingList) UnsubscribeBlocked() (UnsubscribeMethod, string) {
+	u, ok := rm.Unsubscriber.(*linkUnsubscriber)
+	if !ok {
+		return MethodNone, ""
+	}
+	return u.Blocked()
 }
 
 func (rm MailingList) UnsubscribeAvailable() bool {
 	return rm.Unsubscriber != nil
 }
+
+func (rm MailingList) UnsubscribeMethod() UnsubscribeMethod {
+	if rm.Unsubscriber == nil {
+		return MethodNone
+	}
+	return rm.Unsubscriber.Method()
+}
+
+// LastUnsubscribeAttempt returns the outcome of the latest unsubscribe, if
+// any was made.
+func (m MailingList) LastUnsubscribeAttempt() (Attempt, bool) {
+	if m.Unsubscriber == nil {
+		return Attempt{}, false
+	}
+	attempts := m.Unsubscriber.Attempts()
+	if len(attempts) == 0 {
+		return Attempt{}, false
+	}
+	return attempts[len(attempts)-1], true
+}
+
+// OpenUnsubscribeLink hands link based unsubscriptions over to the browser.
+func (m MailingList) OpenUnsubscribeLink() error {
+	u, ok := m.Unsubscriber.(*linkUnsubscriber)
+	if !ok {
+		return ErrNoUnsubscriber
+	}
+	return u.Open()
+}
@@ -0,0 +1,137 @@
+package inbox
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultMailtoSubject = "unsubscribe"
+
+// Mailer sends plain text messages from the mailbox being cleaned up and
+// returns the ID of the sent message.
+type Mailer interface {
+	SendMail(ctx context.Context, to []string, subject, body string) (string, error)
+}
+
+type mailtoUnsubscriber struct {
+	attemptLog
+	mailer  Mailer
+	target  *url.URL
+	to      []string
+	subject string
+	body    string
+}
+
+func NewMailtoUnsubscriber(headers map[string][]string, mailer Mailer) *mailtoUnsubscriber {
+	for _, u := range unsubscribeTargets(headers) {
+		if u.Scheme != "mailto" {
+			continue
+		}
+		to, subject, body, err := parseMailto(u)
+		if err != nil {
+			continue
+		}
+		return &mailtoUnsubscriber{
+			mailer:  mailer,
+			target:  u,
+			to:      to,
+			subject: subject,
+			body:    body,
+		}
+	}
+	return nil
+}
+
+func (u *mailtoUnsubscriber) Method() UnsubscribeMethod {
+	return MethodMailto
+}
+
+func (u *mailtoUnsubscriber) Do(ctx context.Context) error {
+	if u == nil {
+		return ErrNoUnsubscriber
+	}
+
+	attempt := Attempt{
+		Method: MethodMailto,
+		Target: u.target.String(),
+		At:     time.Now(),
+	}
+
+	id, err := u.mailer.SendMail(ctx, u.to, u.subject, u.body)
+	if err != nil {
+		attempt.Err = fmt.Errorf("cannot send unsubscribe mail to %s: %w", strings.Join(u.to, ", "), err)
+		return u.record(attempt)
+	}
+	attempt.MessageID = id
+	attempt.Detail = fmt.Sprintf("sent message %s", id)
+	return u.record(attempt)
+}
+
+func (u *mailtoUnsubscriber) Target() string {
+	return u.target.String()
+}
+
+// parseMailto extracts recipients, subject and body from a RFC 6068 URI such
+// as mailto:list@example.com?subject=unsubscribe&body=please
+func parseMailto(u *url.URL) (to []string, subject, body string, err error) {
+	addrs := u.Opaque
+	if addrs == "" {
+		addrs = u.Path
+	}
+	addrs, err = url.PathUnescape(addrs)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("malformed mailto: %w", err)
+	}
+
+	// fields are split by hand, as url.ParseQuery would turn + into a space
+	// where RFC 6068 takes it literally
+	candidates := strings.Split(addrs, ",")
+	for _, field := range strings.Split(u.RawQuery, "&") {
+		if field == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(field, "=")
+		if k, err = url.PathUnescape(k); err != nil {
+			return nil, "", "", fmt.Errorf("malformed mailto query: %w", err)
+		}
+		if v, err = url.PathUnescape(v); err != nil {
+			return nil, "", "", fmt.Errorf("malformed mailto query: %w", err)
+		}
+		switch strings.ToLower(k) {
+		case "to":
+			candidates = append(candidates, strings.Split(v, ",")...)
+		case "subject":
+			if subject == "" {
+				subject = v
+			}
+		case "body":
+			if body == "" {
+				body = v
+			}
+		}
+	}
+
+	for _, c := range candidates {
+		if strings.TrimSpace(c) == "" {
+			continue
+		}
+		addr, err := mail.ParseAddress(c)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("malformed mailto recipient %q: %w", c, err)
+		}
+		to = append(to, addr.Address)
+	}
+	if len(to) == 0 {
+		return nil, "", "", fmt.Errorf("malformed mailto: no recipients")
+	}
+
+	if strings.TrimSpace(subject) == "" {
+		subject = defaultMailtoSubject
+	}
+
+	return to, subject, body, nil
+}
@@ -0,0 +1,91 @@
+package inbox
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseMailto(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		to      []string
+		subject string
+		body    string
+		wantErr bool
+	}{
+		{
+			name:    "subject and body",
+			uri:     "mailto:list@example.com?subject=unsubscribe%20me&body=please",
+			to:      []string{"list@example.com"},
+			subject: "unsubscribe me",
+			body:    "please",
+		},
+		{
+			name:    "literal plus",
+			uri:     "mailto:list+unsub@example.com?subject=unsub+123&body=a+b",
+			to:      []string{"list+unsub@example.com"},
+			subject: "unsub+123",
+			body:    "a+b",
+		},
+		{
+			name:    "encoded plus",
+			uri:     "mailto:list%2Bunsub@example.com?subject=unsub%2B123",
+			to:      []string{"list+unsub@example.com"},
+			subject: "unsub+123",
+		},
+		{
+			name:    "recipient in the query",
+			uri:     "mailto:?to=list@example.com&subject=bye",
+			to:      []string{"list@example.com"},
+			subject: "bye",
+		},
+		{
+			name:    "several recipients",
+			uri:     "mailto:a@example.com,b@example.com?to=c@example.com",
+			to:      []string{"a@example.com", "b@example.com", "c@example.com"},
+			subject: defaultMailtoSubject,
+		},
+		{
+			name:    "missing subject",
+			uri:     "mailto:list@example.com",
+			to:      []string{"list@example.com"},
+			subject: defaultMailtoSubject,
+		},
+		{
+			name:    "blank subject",
+			uri:     "mailto:list@example.com?subject=%20",
+			to:      []string{"list@example.com"},
+			subject: defaultMailtoSubject,
+		},
+		{
+			name:    "no recipients",
+			uri:     "mailto:?subject=bye",
+			wantErr: true,
+		},
+		{
+			name:    "malformed recipient",
+			uri:     "mailto:not-an-address",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.uri)
+			if err != nil {
+				t.Fatal(err)
+			}
+			to, subject, body, err := parseMailto(u)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMailto(%q) error = %v, want error %v", tt.uri, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(to, tt.to) || subject != tt.subject || body != tt.body {
+				t.Errorf("parseMailto(%q) = %v, %q, %q, want %v, %q, %q", tt.uri, to, subject, body, tt.to, tt.subject, tt.body)
+			}
+		})
+	}
+}
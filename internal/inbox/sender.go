@@ -0,0 +1,151 @@
+package inbox
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	originalFromHeaderKey   = "x-original-from"
+	originalSenderHeaderKey = "x-original-sender"
+	replyToHeaderKey        = "reply-to"
+	senderHeaderKey         = "sender"
+	arcAuthResultsKey       = "arc-authentication-results"
+)
+
+// how Google Groups and most lists rewriting From for DMARC name the relay,
+// e.g. "Jane Doe via Hiking Club"
+var viaNameRe = regexp.MustCompile(`^(.+?)\s+via\s+(\S.*)$`)
+
+var arcHeaderFromRe = regexp.MustCompile(`(?i)\bheader\.from=([^\s;]+)`)
+
+// resolveAuthor finds who actually wrote a mail that a list or forwarder
+// relayed under its own address. X-Original-From and X-Original-Sender, as
+// set by Google Groups, are trusted when present. Otherwise, mails whose From
+// was rewritten are attributed to the Reply-To or Sender address matching the
+// domain ARC recorded for the original From, or failing that to the first of
+// them that is not the relay itself.
+func resolveAuthor(rm RawMail) (addr, name string) {
+	for _, key := range []string{originalFromHeaderKey, originalSenderHeaderKey} {
+		if vals := rm.Headers[key]; len(vals) > 0 {
+			if a, _ := ParseAddress(vals[0]); a != nil && !strings.EqualFold(a.Address, rm.From) {
+				return strings.ToLower(a.Address), firstNonEmpty(a.Name, viaName(rm.Name, rm.From))
+			}
+		}
+	}
+
+	arcDomain := arcOriginalDomain(rm.Headers)
+	via := viaName(rm.Name, rm.From)
+	relayed := via != "" || (arcDomain != "" && !alignedDomains(arcDomain, domainOf(rm.From)))
+	if !relayed {
+		return rm.From, rm.Name
+	}
+
+	replyTo, _ := ParseAddresses(rm.Headers[replyToHeaderKey])
+	sender, _ := ParseAddresses(rm.Headers[senderHeaderKey])
+	var fallback *mail.Address
+	for _, a := range append(replyTo, sender...) {
+		if strings.EqualFold(a.Address, rm.From) {
+			continue
+		}
+		if arcDomain != "" && alignedDomains(arcDomain, domainOf(a.Address)) {
+			return strings.ToLower(a.Address), firstNonEmpty(a.Name, via)
+		}
+		if fallback == nil {
+			fallback = a
+		}
+	}
+	if fallback != nil {
+		return strings.ToLower(fallback.Address), firstNonEmpty(fallback.Name, via)
+	}
+	return rm.From, firstNonEmpty(via, rm.Name)
+}
+
+// viaName returns the author name out of a display name rewritten by a
+// relay, empty when it was not. The relay must be the one in the From
+// address, so that names such as "Parcel via UPS" sent by a shop are left
+// alone.
+func viaName(name, from string) string {
+	m := viaNameRe.FindStringSubmatch(name)
+	if m == nil || !isRelay(m[2], from) {
+		return ""
+	}
+	return m[1]
+}
+
+// isRelay tells whether relay, as named in a display name, is the From
+// domain or one of its labels, or the From local part, as Google Groups
+// sends from the group address.
+func isRelay(relay, from string) bool {
+	relay = strings.ToLower(strings.TrimSpace(relay))
+	local, domain, ok := strings.Cut(strings.ToLower(from), "@")
+	if !ok {
+		return false
+	}
+	if strings.Contains(relay, ".") && alignedDomains(relay, domain) {
+		return true
+	}
+	name := alphanumeric(relay)
+	if name == "" {
+		return false
+	}
+	if name == alphanumeric(local) {
+		return true
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if name == alphanumeric(label) {
+			return true
+		}
+	}
+	return false
+}
+
+// alphanumeric drops everything but letters and digits, so that "Hiking
+// Club" compares equal to "hiking-club".
+func alphanumeric(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// arcOriginalDomain returns the From domain recorded by the first hop of an
+// ARC chain, before any intermediary rewrote it.
+func arcOriginalDomain(headers map[string][]string) string {
+	for _, v := range headers[arcAuthResultsKey] {
+		if !strings.HasPrefix(strings.TrimSpace(v), "i=1;") {
+			continue
+		}
+		if m := arcHeaderFromRe.FindStringSubmatch(v); m != nil {
+			return strings.ToLower(strings.TrimSuffix(m[1], ";"))
+		}
+	}
+	return ""
+}
+
+func domainOf(addr string) string {
+	_, domain, _ := strings.Cut(addr, "@")
+	return strings.ToLower(domain)
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// WithAuthor resolves the original sender of relayed mail. It must come
+// after WithSender and WithHeaders.
+func WithAuthor() RawMailOpt {
+	return func(rm *RawMail) error {
+		rm.Author, rm.AuthorName = resolveAuthor(*rm)
+		return nil
+	}
+}
@@ -0,0 +1,146 @@
+package inbox
+
+import "testing"
+
+func TestResolveAuthor(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     string
+		fromName string
+		headers  map[string][]string
+		wantAddr string
+		wantName string
+	}{
+		{
+			name:     "not relayed",
+			from:     "news@shop.com",
+			fromName: "Shop",
+			wantAddr: "news@shop.com",
+			wantName: "Shop",
+		},
+		{
+			name:     "via group",
+			from:     "hiking-club@googlegroups.com",
+			fromName: "Jane Doe via Hiking Club",
+			headers: map[string][]string{
+				replyToHeaderKey: {"Jane Doe <Jane@example.com>"},
+			},
+			wantAddr: "jane@example.com",
+			wantName: "Jane Doe",
+		},
+		{
+			name:     "via group without reply-to",
+			from:     "hiking-club@googlegroups.com",
+			fromName: "Jane Doe via Hiking Club",
+			wantAddr: "hiking-club@googlegroups.com",
+			wantName: "Jane Doe",
+		},
+		{
+			name:     "x-original-from",
+			from:     "hiking-club@googlegroups.com",
+			fromName: "Jane Doe via Hiking Club",
+			headers: map[string][]string{
+				originalFromHeaderKey: {"jane@example.com"},
+				replyToHeaderKey:      {"someone@else.com"},
+			},
+			wantAddr: "jane@example.com",
+			wantName: "Jane Doe",
+		},
+		{
+			name:     "x-original-from naming the relay",
+			from:     "list@lists.example.org",
+			fromName: "List",
+			headers: map[string][]string{
+				originalFromHeaderKey: {"List <list@lists.example.org>"},
+			},
+			wantAddr: "list@lists.example.org",
+			wantName: "List",
+		},
+		{
+			name:     "arc header.from",
+			from:     "list@lists.example.org",
+			fromName: "Example List",
+			headers: map[string][]string{
+				arcAuthResultsKey: {"i=1; mx.google.com; dmarc=pass header.from=author.net;"},
+				replyToHeaderKey:  {"list@lists.example.org", "Other <other@else.com>"},
+				senderHeaderKey:   {"Author <bob@mail.author.net>"},
+			},
+			wantAddr: "bob@mail.author.net",
+			wantName: "Author",
+		},
+		{
+			name:     "arc header.from of a later hop",
+			from:     "list@lists.example.org",
+			fromName: "Example List",
+			headers: map[string][]string{
+				arcAuthResultsKey: {"i=2; mx.google.com; dmarc=pass header.from=author.net"},
+				senderHeaderKey:   {"bob@author.net"},
+			},
+			wantAddr: "list@lists.example.org",
+			wantName: "Example List",
+		},
+		{
+			name:     "shop naming its carrier",
+			from:     "orders@shop.com",
+			fromName: "Parcel via UPS",
+			headers: map[string][]string{
+				replyToHeaderKey: {"support@ups.com"},
+			},
+			wantAddr: "orders@shop.com",
+			wantName: "Parcel via UPS",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, name := resolveAuthor(RawMail{From: tt.from, Name: tt.fromName, Headers: tt.headers})
+			if addr != tt.wantAddr || name != tt.wantName {
+				t.Errorf("resolveAuthor() = %q, %q, want %q, %q", addr, name, tt.wantAddr, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestViaName(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		want string
+	}{
+		{"Jane Doe via Hiking Club", "hiking-club@googlegroups.com", "Jane Doe"},
+		{"Jane Doe via example.org", "list@lists.example.org", "Jane Doe"},
+		{"Jane Doe via Lists", "announce@lists.example.org", "Jane Doe"},
+		{"Parcel via UPS", "orders@shop.com", ""},
+		{"Jane Doe", "jane@example.com", ""},
+		{"via Hiking Club", "hiking-club@googlegroups.com", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := viaName(tt.name, tt.from); got != tt.want {
+				t.Errorf("viaName(%q, %q) = %q, want %q", tt.name, tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRelay(t *testing.T) {
+	tests := []struct {
+		relay string
+		from  string
+		want  bool
+	}{
+		{"Hiking Club", "hiking-club@googlegroups.com", true},
+		{"googlegroups", "hiking-club@googlegroups.com", true},
+		{"example.org", "list@lists.example.org", true},
+		{"UPS", "orders@shop.com", false},
+		{"ups.com", "orders@shop.com", false},
+		{"---", "hiking-club@googlegroups.com", false},
+		{"Hiking Club", "not an address", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.relay+" "+tt.from, func(t *testing.T) {
+			if got := isRelay(tt.relay, tt.from); got != tt.want {
+				t.Errorf("isRelay(%q, %q) = %v, want %v", tt.relay, tt.from, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package inbox
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SortOrder ranks mailing lists, the worst offenders first.
+type SortOrder int
+
+const (
+	SortByUnreads SortOrder = iota
+	// mailbox storage used by the list
+	SortBySize
+	// lowest open rate, needs engagement to be measured
+	SortByEngagement
+)
+
+var sortOrderNames = map[SortOrder]string{
+	SortByUnreads:    "unreads",
+	SortBySize:       "size",
+	SortByEngagement: "engagement",
+}
+
+func (o SortOrder) String() string {
+	return sortOrderNames[o]
+}
+
+func ParseSortOrder(s string) (SortOrder, error) {
+	for o, name := range sortOrderNames {
+		if strings.EqualFold(s, name) {
+			return o, nil
+		}
+	}
+	return SortByUnreads, fmt.Errorf("unknown sort order %q", s)
+}
+
+// Next cycles through the available orders, so the TUI can toggle them.
+func (o SortOrder) Next() SortOrder {
+	return (o + 1) % SortOrder(len(sortOrderNames))
+}
+
+// WithSortOrder sets how lists are ranked, by unread count if not given.
+func WithSortOrder(o SortOrder) GroupOpt {
+	return func(c *groupConfig) {
+		c.order = o
+	}
+}
+
+// SortMailingLists ranks l in place.
+func SortMailingLists(l []MailingList, o SortOrder) {
+	switch o {
+	case SortBySize:
+		sortDescendingByTotalBytes(l)
+	case SortByEngagement:
+		sortAscendingByOpenRate(l)
+	default:
+		sortAscendingByTotalUnreads(l)
+	}
+}
+
+func sortDescendingByTotalBytes(l []MailingList) {
+	slices.SortStableFunc(l, func(a, b MailingList) int {
+		return cmp.Or(cmp.Compare(b.TotalBytes, a.TotalBytes), cmp.Compare(a.Key, b.Key))
+	})
+}
+
+// FormatBytes renders a size the way Gmail reports storage, e.g. "3.2 MB".
+func FormatBytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
@@ -0,0 +1,75 @@
+package inbox
+
+import (
+	"regexp"
+	"strings"
+)
+
+const noSubjectTemplate = "(no subject)"
+
+const months = `(?:jan|feb|mar|apr|may|jun|jul|aug|sep|sept|oct|nov|dec)[a-z]*\.?`
+
+var (
+	replyPrefixRe = regexp.MustCompile(`(?i)^\s*(?:(?:re|fwd?|aw|wg|tr|sv)\s*(?:\[\d+\])?\s*:\s*)+`)
+
+	// variable parts of a subject, replaced in order by a wildcard
+	templateRes = []*regexp.Regexp{
+		regexp.MustCompile(`https?://\S+`),
+		regexp.MustCompile(`[\w.+-]+@[\w-]+(?:\.[\w-]+)+`),
+		regexp.MustCompile(`(?i)\b` + months + `\s+\d{1,2}(?:st|nd|rd|th)?(?:,?\s+\d{4})?\b`),
+		regexp.MustCompile(`(?i)\b\d{1,2}(?:st|nd|rd|th)?\s+` + months + `(?:,?\s+\d{4})?\b`),
+		regexp.MustCompile(`\b\d{1,4}[-/.]\d{1,2}[-/.]\d{1,4}\b`),
+		regexp.MustCompile(`(?i)\b\d{1,2}:\d{2}(?::\d{2})?(?:\s*[ap]m)?\b`),
+		// order numbers, tracking codes and amounts: any token with a digit
+		regexp.MustCompile(`\b[\p{L}_]*\d[\p{L}\d_-]*`),
+	}
+
+	// names right after a greeting, as in "Hi Jane, your order shipped",
+	// only the greeting ignoring case so the name must be capitalized
+	greetingNameRe = regexp.MustCompile(`\b((?i:hi|hello|hey|dear|welcome|thanks|congrats|congratulations)),?\s+\p{Lu}\p{Ll}+`)
+
+	currencyRe      = regexp.MustCompile(`[$€£¥]\s*\*|\*\s*(?i:usd|eur|gbp|%)`)
+	repeatedStarsRe = regexp.MustCompile(`\*(?:[\s,.:/-]*\*)+`)
+)
+
+// SubjectTemplate normalizes a subject into what every mail of the same kind
+// shares, replacing dates, numbers, order IDs, addresses and greeted names
+// with "*", e.g. "Your weekly digest #42" becomes "Your weekly digest #*".
+// Templates are shown as is, so control characters are dropped.
+func SubjectTemplate(subject string) string {
+	t := replyPrefixRe.ReplaceAllString(Sanitize(subject), "")
+	for _, re := range templateRes {
+		t = re.ReplaceAllString(t, "*")
+	}
+	t = greetingNameRe.ReplaceAllString(t, "$1 *")
+	t = currencyRe.ReplaceAllString(t, "*")
+	t = repeatedStarsRe.ReplaceAllString(t, "*")
+	t = strings.Join(strings.Fields(t), " ")
+	if t == "" {
+		return noSubjectTemplate
+	}
+	return t
+}
+
+// splitTemplates breaks parent down into one sub-list per subject template,
+// unless every mail in it follows the same one.
+func splitTemplates(mails RawMailList, cfg groupConfig, parent MailingList) []MailingList {
+	groups := mails.GroupBy(func(rm RawMail) string {
+		return SubjectTemplate(rm.Subject)
+	})
+	if len(groups) < 2 {
+		return nil
+	}
+
+	templateCfg := cfg
+	templateCfg.subgroup = true
+	templateCfg.parent = &parent
+	templates := make([]MailingList, 0, len(groups))
+	for t, tm := range groups {
+		if list, ok := newMailingList(t, tm, templateCfg); ok {
+			templates = append(templates, list)
+		}
+	}
+	sortAscendingByTotalUnreads(templates)
+	return templates
+}
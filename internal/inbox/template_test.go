@@ -0,0 +1,93 @@
+package inbox
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSubjectTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		want    string
+	}{
+		{"issue number", "Your weekly digest #42", "Your weekly digest #*"},
+		{"amount", "You paid $12.50 to Acme", "You paid * to Acme"},
+		{"order ID", "Order A1B2C3 has shipped", "Order * has shipped"},
+		{"tracking code", "Package 1Z999AA10123456784 is out for delivery", "Package * is out for delivery"},
+		{"hyphenated ID", "Ticket INC-20391 updated", "Ticket INC-* updated"},
+		{"ISO date", "Statement for 2026-01-31", "Statement for *"},
+		{"slashed date", "Invoice due 31/01/2026", "Invoice due *"},
+		{"month then day", "Your summary for Jan 5, 2026", "Your summary for *"},
+		{"day then month", "Meetup on 5th March", "Meetup on *"},
+		{"time", "Reminder: call at 10:30 am", "Reminder: call at *"},
+		{"address", "New login for jane@example.com", "New login for *"},
+		{"link", "Read more at https://example.com/a/1", "Read more at *"},
+		{"greeted name", "Hi Jane, your order shipped", "Hi *, your order shipped"},
+		{"reply prefixes", "Re: Fwd: Weekly digest", "Weekly digest"},
+		{"no variable part", "Welcome to the club", "Welcome to the club"},
+		{"only variable parts", "12345", "*"},
+		{"control characters", "Hello\x1b[* world\x07", "Hello[* world"},
+		{"line breaks", "Weekly\r\n\tdigest", "Weekly digest"},
+		{"empty", "", noSubjectTemplate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SubjectTemplate(tt.subject); got != tt.want {
+				t.Errorf("SubjectTemplate(%q) = %q, want %q", tt.subject, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitTemplatesSharesParent(t *testing.T) {
+	mail := func(id, subject string) RawMail {
+		rm := fixture("news@example.com", map[string]string{"list-unsubscribe": "<mailto:unsubscribe@example.com>"})
+		rm.ID, rm.Subject = id, subject
+		return rm
+	}
+	lists := GetMailingList(RawMailList{
+		mail("1", "Your weekly digest #41"),
+		mail("2", "Your weekly digest #42"),
+		mail("3", "Order 1234 shipped"),
+	}, WithMailer(nopMailer{}))
+	if len(lists) != 1 {
+		t.Fatalf("got %d lists, want 1", len(lists))
+	}
+	list := lists[0]
+	if len(list.Templates) != 2 {
+		t.Fatalf("got %d templates, want 2", len(list.Templates))
+	}
+	for _, tmpl := range list.Templates {
+		if tmpl.Unsubscriber != list.Unsubscriber {
+			t.Errorf("template %q has an unsubscriber of its own", tmpl.Key)
+		}
+		if tmpl.Classification.Kind != list.Classification.Kind {
+			t.Errorf("template %q classified as %s, list as %s", tmpl.Key, tmpl.Classification.Kind, list.Classification.Kind)
+		}
+	}
+}
+
+type nopMailer struct{}
+
+func (nopMailer) SendMail(context.Context, []string, string, string) (string, error) {
+	return "", nil
+}
+
+func TestSortMailingListsBreaksTiesByKey(t *testing.T) {
+	for _, order := range []SortOrder{SortByUnreads, SortBySize, SortByEngagement} {
+		t.Run(order.String(), func(t *testing.T) {
+			l := []MailingList{
+				{Key: "c", TotalUnreads: 1, TotalBytes: 10},
+				{Key: "a", TotalUnreads: 1, TotalBytes: 10},
+				{Key: "b", TotalUnreads: 1, TotalBytes: 10},
+			}
+			SortMailingLists(l, order)
+			for i, want := range []string{"a", "b", "c"} {
+				if l[i].Key != want {
+					t.Fatalf("sorted as %s, %s, %s, want a, b, c", l[0].Key, l[1].Key, l[2].Key)
+				}
+			}
+		})
+	}
+}
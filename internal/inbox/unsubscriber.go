@@ -8,6 +8,7 @@ import (
 	"net/url"
 	"regexp"
 	"strings"
+	"time"
 )
 
 var ErrNoUnsubscriber = errors.New("no unsubscriber")
@@ -17,11 +18,84 @@ const (
 	postUnsubscribeHeaderKey = "list-unsubscribe-post"
 )
 
-type unsubscriber struct {
+var unsubscribeTargetRe = regexp.MustCompile(`<([^>]+)>`)
+
+type UnsubscribeMethod int
+
+const (
+	MethodNone UnsubscribeMethod = iota
+	MethodOneClick
+	MethodMailto
+	MethodLink
+)
+
+func (m UnsubscribeMethod) String() string {
+	switch m {
+	case MethodOneClick:
+		return "one-click"
+	case MethodMailto:
+		return "mailto"
+	case MethodLink:
+		return "link"
+	default:
+		return "none"
+	}
+}
+
+type Unsubscriber interface {
+	Method() UnsubscribeMethod
+	// Target is the URI the unsubscribe request is sent to
+	Target() string
+	Do(ctx context.Context) error
+	// Attempts lists the outcome of every call to Do, oldest first
+	Attempts() []Attempt
+}
+
+// NewUnsubscriber picks the best unsubscribe strategy advertised by headers,
+// preferring the ones that need no user interaction: one-click, then mailto
+// and finally a plain link. mailer may be nil, in which case mailto targets
+// are ignored.
+//
+// One-click and mailto are only honored for mails whose DKIM signature is
+// aligned and covers the unsubscribe headers, as both run without asking.
+// Otherwise, the link is flagged and left for the user to confirm.
+func NewUnsubscriber(headers map[string][]string, mailer Mailer) Unsubscriber {
+	var (
+		blockedMethod UnsubscribeMethod
+		blocked       string
+	)
+	if u := NewOneClickUnsubscriber(headers); u != nil {
+		alignment := CheckDKIMAlignment(headers)
+		if alignment.Aligned {
+			return u
+		}
+		blockedMethod, blocked = MethodOneClick, alignment.Reason
+	}
+	if mailer != nil {
+		if u := NewMailtoUnsubscriber(headers, mailer); u != nil {
+			// nothing else tells who picked the address we would mail
+			alignment := checkDKIMAlignment(headers, fromHeaderKey, unsubscribeHeaderKey)
+			if alignment.Aligned {
+				return u
+			}
+			if blocked == "" {
+				blockedMethod, blocked = MethodMailto, alignment.Reason
+			}
+		}
+	}
+	if u := NewLinkUnsubscriber(headers); u != nil {
+		u.blockedMethod, u.blocked = blockedMethod, blocked
+		return u
+	}
+	return nil
+}
+
+type oneClickUnsubscriber struct {
+	attemptLog
 	target *url.URL
 }
 
-func NewOneClickUnsubscriber(headers map[string][]string) *unsubscriber {
+func NewOneClickUnsubscriber(headers map[string][]string) *oneClickUnsubscriber {
 	postHeader, ok := headers[postUnsubscribeHeaderKey]
 	if !ok || len(postHeader) == 0 {
 		return nil
@@ -31,24 +105,8 @@ func NewOneClickUnsubscriber(headers map[string][]string) *unsubscriber {
 		return nil
 	}
 
-	listVals, ok := headers[unsubscribeHeaderKey]
-	if !ok || len(listVals) == 0 {
-		return nil
-	}
-
-	re := regexp.MustCompile(`<([^>]+)>`)
-	matches := re.FindAllStringSubmatch(listVals[0], -1)
-	if len(matches) == 0 {
-		return nil
-	}
-
 	var target *url.URL
-	for _, m := range matches {
-		u, err := url.Parse(strings.TrimSpace(m[1]))
-		if err != nil {
-			continue
-		}
-
+	for _, u := range unsubscribeTargets(headers) {
 		if u.Scheme == "https" {
 			target = u
 			break
@@ -59,14 +117,28 @@ func NewOneClickUnsubscriber(headers map[string][]string) *unsubscriber {
 		return nil
 	}
 
-	return &unsubscriber{target: target}
+	return &oneClickUnsubscriber{target: target}
+}
+
+func (u *oneClickUnsubscriber) Method() UnsubscribeMethod {
+	return MethodOneClick
+}
+
+func (u *oneClickUnsubscriber) Target() string {
+	return u.target.String()
 }
 
-func (u *unsubscriber) Do(ctx context.Context) error {
+func (u *oneClickUnsubscriber) Do(ctx context.Context) error {
 	if u == nil {
 		return ErrNoUnsubscriber
 	}
 
+	attempt := Attempt{
+		Method: MethodOneClick,
+		Target: u.target.String(),
+		At:     time.Now(),
+	}
+
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodPost,
@@ -74,18 +146,34 @@ func (u *unsubscriber) Do(ctx context.Context) error {
 		strings.NewReader("List-Unsubscribe=One-Click"),
 	)
 	if err != nil {
-		return err
+		attempt.Err = err
+		return u.record(attempt)
 	}
-
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("User-Agent", "GheeMail/1.0")
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
+	attempt.Status, attempt.Err = doUnsubscribeRequest(req, nil)
+	if attempt.Status != 0 {
+		attempt.Detail = fmt.Sprintf("%d %s", attempt.Status, http.StatusText(attempt.Status))
 	}
-	if resp.StatusCode < 200 && resp.StatusCode >= 300 {
-		return fmt.Errorf("failed req, status %s", resp.Status)
+	return u.record(attempt)
+}
+
+// unsubscribeTargets returns every URI enclosed in angle brackets in the
+// List-Unsubscribe header, in the order the sender listed them.
+func unsubscribeTargets(headers map[string][]string) []*url.URL {
+	listVals, ok := headers[unsubscribeHeaderKey]
+	if !ok || len(listVals) == 0 {
+		return nil
 	}
-	return nil
+
+	matches := unsubscribeTargetRe.FindAllStringSubmatch(listVals[0], -1)
+	targets := make([]*url.URL, 0, len(matches))
+	for _, m := range matches {
+		u, err := url.Parse(strings.TrimSpace(m[1]))
+		if err != nil {
+			continue
+		}
+		targets = append(targets, u)
+	}
+	return targets
 }
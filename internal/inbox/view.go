@@ -0,0 +1,82 @@
+package inbox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ViewMode decides which senders are worth showing at all.
+type ViewMode int
+
+const (
+	// senders of mails carrying List-Unsubscribe, whether or not it can be
+	// acted on, or confidently classified as lists or bulk mail
+	ViewMailingLists ViewMode = iota
+	// only lists geemail can unsubscribe from without any interaction
+	ViewOneClick
+	// every sender, such as notification bots and receipts that are not
+	// lists but still pile up
+	ViewAllSenders
+)
+
+var viewModeNames = map[ViewMode]string{
+	ViewMailingLists: "lists",
+	ViewOneClick:     "one-click",
+	ViewAllSenders:   "all",
+}
+
+func (v ViewMode) String() string {
+	return viewModeNames[v]
+}
+
+func ParseViewMode(s string) (ViewMode, error) {
+	for v, name := range viewModeNames {
+		if strings.EqualFold(s, name) {
+			return v, nil
+		}
+	}
+	return ViewMailingLists, fmt.Errorf("unknown view %q", s)
+}
+
+// Next cycles through the available modes, so the TUI can toggle them.
+func (v ViewMode) Next() ViewMode {
+	return (v + 1) % ViewMode(len(viewModeNames))
+}
+
+// WithViewMode sets which senders make it into the lists, only mailing lists
+// if not given.
+func WithViewMode(v ViewMode) GroupOpt {
+	return func(c *groupConfig) {
+		c.view = v
+	}
+}
+
+// how confident the classifier must be before mail without List-Unsubscribe
+// is shown among the lists
+const listConfidence = 0.6
+
+func (v ViewMode) includes(rm RawMail, c *Classifier) bool {
+	if v == ViewAllSenders || isMailingList(rm) {
+		return true
+	}
+	cl := c.Classify(rm)
+	return (cl.Kind == KindList || cl.Kind == KindBulk) && cl.Confidence >= listConfidence
+}
+
+func (v ViewMode) keeps(l MailingList) bool {
+	return v != ViewOneClick || l.UnsubscribeMethod() == MethodOneClick
+}
+
+// Icon tells the unsubscribe method apart at a glance in the TUI.
+func (m UnsubscribeMethod) Icon() string {
+	switch m {
+	case MethodOneClick:
+		return "●"
+	case MethodMailto:
+		return "✉"
+	case MethodLink:
+		return "↗"
+	default:
+		return "○"
+	}
+}
@@ -0,0 +1,97 @@
+package journal
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sverdejot/geemail/internal/inbox"
+	"github.com/sverdejot/geemail/internal/store"
+)
+
+const (
+	journalFile = "geemail-journal"
+	journalDesc = "unsubscribe journal"
+)
+
+// Entry records a successful unsubscribe.
+type Entry struct {
+	Sender string    `json:"sender"`
+	ListID string    `json:"list_id,omitempty"`
+	Method string    `json:"method"`
+	Target string    `json:"target,omitempty"`
+	At     time.Time `json:"at"`
+	// HTTP status of the request, zero for methods not going over HTTP
+	Status int `json:"status,omitempty"`
+	// ID of the unsubscribe mail sent, for mailto only
+	MessageID string `json:"message_id,omitempty"`
+}
+
+// Journal keeps every unsubscribe across runs, so later scans can tell which
+// lists kept mailing afterwards.
+type Journal struct {
+	mu      sync.Mutex
+	file    *store.File
+	entries []Entry
+}
+
+// Open loads the journal of mailbox from the user config directory, starting
+// an empty one if there is none yet, or if it cannot be read, in which case
+// store.ErrCorrupt is returned as well. An empty mailbox stands for the
+// account signed in through OAuth.
+func Open(mailbox string) (*Journal, error) {
+	file, entries, err := store.Open[[]Entry](store.MailboxFile(journalFile, mailbox), journalDesc)
+	if file == nil {
+		return nil, err
+	}
+	return &Journal{file: file, entries: entries}, err
+}
+
+// Record appends the latest successful attempt on list and persists the
+// journal.
+func (j *Journal) Record(list inbox.MailingList, attempt inbox.Attempt) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.entries = append(j.entries, Entry{
+		Sender:    list.From,
+		ListID:    list.ListID,
+		Method:    attempt.Method.String(),
+		Target:    attempt.Target,
+		At:        attempt.At,
+		Status:    attempt.Status,
+		MessageID: attempt.MessageID,
+	})
+	return j.file.Save(j.entries)
+}
+
+// Lookup returns the latest unsubscribe from list, matching by List-Id when
+// both sides have one and by sender otherwise.
+func (j *Journal) Lookup(list inbox.MailingList) (Entry, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for i := len(j.entries) - 1; i >= 0; i-- {
+		e := j.entries[i]
+		if list.ListID != "" && e.ListID != "" {
+			if strings.EqualFold(list.ListID, e.ListID) {
+				return e, true
+			}
+			continue
+		}
+		if strings.EqualFold(list.From, e.Sender) {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Annotate stamps every list the user already unsubscribed from with the
+// date of the unsubscribe.
+func (j *Journal) Annotate(lists []inbox.MailingList) {
+	for i := range lists {
+		if e, ok := j.Lookup(lists[i]); ok {
+			lists[i].UnsubscribedAt = e.At
+		}
+	}
+}
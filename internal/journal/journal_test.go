@@ -0,0 +1,114 @@
+package journal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sverdejot/geemail/internal/inbox"
+)
+
+// openTemp opens a journal in a home directory of its own.
+func openTemp(t *testing.T) *Journal {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	j, err := Open("")
+	if err != nil {
+		t.Fatalf("opening journal: %v", err)
+	}
+	return j
+}
+
+func record(t *testing.T, j *Journal, from, listID string, at time.Time) {
+	t.Helper()
+	list := inbox.MailingList{From: from, ListID: listID}
+	if err := j.Record(list, inbox.Attempt{Method: inbox.MethodOneClick, Target: "https://example.com/u", At: at, Status: 200}); err != nil {
+		t.Fatalf("recording %s: %v", from, err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	j := openTemp(t)
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	record(t, j, "news@acme.com", "news.acme.com", day(1))
+	record(t, j, "deals@acme.com", "", day(2))
+	record(t, j, "news@acme.com", "news.acme.com", day(3))
+
+	tests := []struct {
+		name  string
+		list  inbox.MailingList
+		found bool
+		at    time.Time
+	}{
+		{"by list-id, latest wins", inbox.MailingList{From: "other@acme.com", ListID: "NEWS.acme.com"}, true, day(3)},
+		{"different list-id", inbox.MailingList{From: "news@acme.com", ListID: "promo.acme.com"}, false, time.Time{}},
+		{"sender fallback", inbox.MailingList{From: "Deals@acme.com"}, true, day(2)},
+		{"sender fallback without list-id on the list", inbox.MailingList{From: "news@acme.com"}, true, day(3)},
+		{"unknown", inbox.MailingList{From: "someone@example.com"}, false, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, ok := j.Lookup(tt.list)
+			if ok != tt.found || !e.At.Equal(tt.at) {
+				t.Errorf("got %v at %v, want %v at %v", ok, e.At, tt.found, tt.at)
+			}
+		})
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	j := openTemp(t)
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	record(t, j, "news@acme.com", "", at)
+
+	lists := []inbox.MailingList{{From: "news@acme.com"}, {From: "friend@example.com"}}
+	j.Annotate(lists)
+	if !lists[0].UnsubscribedAt.Equal(at) {
+		t.Errorf("unsubscribed list stamped with %v, want %v", lists[0].UnsubscribedAt, at)
+	}
+	if !lists[1].UnsubscribedAt.IsZero() {
+		t.Errorf("other list stamped with %v", lists[1].UnsubscribedAt)
+	}
+}
+
+func TestSave(t *testing.T) {
+	j := openTemp(t)
+	record(t, j, "news@acme.com", "news.acme.com", time.Now())
+
+	reopened, err := Open("")
+	if err != nil {
+		t.Fatalf("reopening journal: %v", err)
+	}
+	if e, ok := reopened.Lookup(inbox.MailingList{ListID: "news.acme.com"}); !ok || e.Status != 200 || e.Method != inbox.MethodOneClick.String() {
+		t.Errorf("reopened journal has %+v, %v", e, ok)
+	}
+}
+
+func TestOpenPerMailbox(t *testing.T) {
+	alice := openTemp(t)
+	record(t, alice, "news@acme.com", "", time.Now())
+
+	bob, err := Open("bob@example.com")
+	if err != nil {
+		t.Fatalf("opening bob's journal: %v", err)
+	}
+	if e, ok := bob.Lookup(inbox.MailingList{From: "news@acme.com"}); ok {
+		t.Errorf("bob's journal holds %+v from another mailbox", e)
+	}
+}
+
+func TestRecordMessageID(t *testing.T) {
+	j := openTemp(t)
+	list := inbox.MailingList{From: "news@acme.com"}
+	attempt := inbox.Attempt{Method: inbox.MethodMailto, Target: "mailto:unsub@acme.com", At: time.Now(), MessageID: "18c2f"}
+	if err := j.Record(list, attempt); err != nil {
+		t.Fatalf("recording: %v", err)
+	}
+
+	reopened, err := Open("")
+	if err != nil {
+		t.Fatalf("reopening journal: %v", err)
+	}
+	if e, ok := reopened.Lookup(list); !ok || e.MessageID != "18c2f" {
+		t.Errorf("reopened journal has %+v, %v, want message ID 18c2f", e, ok)
+	}
+}
@@ -0,0 +1,249 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultConcurrency = 2
+	defaultHistory     = 50
+)
+
+var (
+	ErrUnknownOperation = errors.New("unknown operation")
+	ErrNotFailed        = errors.New("only failed operations can be retried")
+)
+
+type Status int
+
+const (
+	Queued Status = iota
+	Running
+	Succeeded
+	Failed
+)
+
+func (s Status) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Succeeded:
+		return "succeeded"
+	case Failed:
+		return "failed"
+	default:
+		return "queued"
+	}
+}
+
+// Done tells whether the operation settled, one way or the other.
+func (s Status) Done() bool {
+	return s == Succeeded || s == Failed
+}
+
+// Operation is a snapshot of some work submitted to a Queue.
+type Operation struct {
+	ID int
+	// what is being done, e.g. "delete"
+	Name string
+	// what it is done to, e.g. a sender
+	Target   string
+	Status   Status
+	Err      error
+	Attempts int
+	// when it was submitted, and when it last settled
+	Queued, Finished time.Time
+
+	run func(context.Context) error
+}
+
+// Queue runs operations in the background, a few at a time, and keeps the
+// latest ones around so they can be listed and retried.
+type Queue struct {
+	ctx     context.Context
+	sem     chan struct{}
+	updates chan Operation
+	history int
+
+	mu     sync.Mutex
+	nextID int
+	ops    []*Operation
+}
+
+type QueueOpt func(*Queue)
+
+// WithConcurrency sets how many operations may run at once, two if not
+// given.
+func WithConcurrency(n int) QueueOpt {
+	return func(q *Queue) {
+		if n > 0 {
+			q.sem = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithHistory sets how many settled operations are kept, fifty if not
+// given.
+func WithHistory(n int) QueueOpt {
+	return func(q *Queue) {
+		q.history = n
+	}
+}
+
+// NewQueue returns a queue running every operation under ctx.
+func NewQueue(ctx context.Context, opts ...QueueOpt) *Queue {
+	q := &Queue{
+		ctx:     ctx,
+		sem:     make(chan struct{}, defaultConcurrency),
+		updates: make(chan Operation, 64),
+		history: defaultHistory,
+	}
+	for _, fn := range opts {
+		fn(q)
+	}
+	return q
+}
+
+// Updates streams a snapshot every time an operation changes status. It must
+// be drained, as operations block on it.
+func (q *Queue) Updates() <-chan Operation {
+	return q.updates
+}
+
+// Submit queues run and returns right away.
+func (q *Queue) Submit(name, target string, run func(context.Context) error) Operation {
+	q.mu.Lock()
+	q.nextID++
+	op := &Operation{
+		ID:     q.nextID,
+		Name:   name,
+		Target: target,
+		Status: Queued,
+		Queued: time.Now(),
+		run:    run,
+	}
+	q.ops = append(q.ops, op)
+	q.prune()
+	snapshot := *op
+	q.mu.Unlock()
+
+	go q.start(op)
+	return snapshot
+}
+
+// Retry queues a failed operation again.
+func (q *Queue) Retry(id int) (Operation, error) {
+	q.mu.Lock()
+	op := q.find(id)
+	if op == nil {
+		q.mu.Unlock()
+		return Operation{}, fmt.Errorf("%w: %d", ErrUnknownOperation, id)
+	}
+	if op.Status != Failed {
+		q.mu.Unlock()
+		return *op, ErrNotFailed
+	}
+	op.Status = Queued
+	op.Err = nil
+	snapshot := *op
+	q.mu.Unlock()
+
+	go q.start(op)
+	return snapshot, nil
+}
+
+// Recent lists the operations kept, newest first.
+func (q *Queue) Recent() []Operation {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	recent := make([]Operation, 0, len(q.ops))
+	for i := len(q.ops) - 1; i >= 0; i-- {
+		recent = append(recent, *q.ops[i])
+	}
+	return recent
+}
+
+// Has tells whether the operation is still kept, those pruned from the
+// history being gone for good.
+func (q *Queue) Has(id int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.find(id) != nil
+}
+
+// Active counts the operations not settled yet.
+func (q *Queue) Active() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var n int
+	for _, op := range q.ops {
+		if !op.Status.Done() {
+			n++
+		}
+	}
+	return n
+}
+
+func (q *Queue) start(op *Operation) {
+	select {
+	case q.sem <- struct{}{}:
+	case <-q.ctx.Done():
+		q.settle(op, q.ctx.Err())
+		return
+	}
+	defer func() { <-q.sem }()
+
+	q.mu.Lock()
+	op.Status = Running
+	op.Attempts++
+	snapshot := *op
+	q.mu.Unlock()
+	q.updates <- snapshot
+
+	q.settle(op, op.run(q.ctx))
+}
+
+func (q *Queue) settle(op *Operation, err error) {
+	q.mu.Lock()
+	op.Err = err
+	op.Status = Succeeded
+	if err != nil {
+		op.Status = Failed
+	}
+	op.Finished = time.Now()
+	snapshot := *op
+	q.mu.Unlock()
+	q.updates <- snapshot
+}
+
+func (q *Queue) find(id int) *Operation {
+	for _, op := range q.ops {
+		if op.ID == id {
+			return op
+		}
+	}
+	return nil
+}
+
+// prune forgets the oldest settled operations beyond the history size.
+func (q *Queue) prune() {
+	excess := len(q.ops) - q.history
+	if excess <= 0 {
+		return
+	}
+	kept := q.ops[:0]
+	for _, op := range q.ops {
+		if excess > 0 && op.Status.Done() {
+			excess--
+			continue
+		}
+		kept = append(kept, op)
+	}
+	q.ops = kept
+}
@@ -0,0 +1,147 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFor reads updates until the operation reaches status, failing after a
+// while so a broken queue does not hang the tests.
+func waitFor(t *testing.T, q *Queue, id int, status Status) Operation {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case op := <-q.Updates():
+			if op.ID == id && op.Status == status {
+				return op
+			}
+		case <-timeout:
+			t.Fatalf("operation %d never reached %s", id, status)
+		}
+	}
+}
+
+func TestQueueConcurrencyLimit(t *testing.T) {
+	const limit, total = 2, 6
+	q := NewQueue(context.Background(), WithConcurrency(limit))
+
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(total)
+	for range total {
+		q.Submit("op", "target", func(context.Context) error {
+			defer wg.Done()
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			<-release
+			running.Add(-1)
+			return nil
+		})
+	}
+
+	go func() {
+		for range q.Updates() {
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := peak.Load(); got != limit {
+		t.Errorf("peak of %d operations running at once, want %d", got, limit)
+	}
+}
+
+func TestQueueStatusTransitions(t *testing.T) {
+	q := NewQueue(context.Background())
+	errBoom := errors.New("boom")
+
+	ok := q.Submit("ok", "a", func(context.Context) error { return nil })
+	if ok.Status != Queued {
+		t.Errorf("submitted operation is %s, want queued", ok.Status)
+	}
+	waitFor(t, q, ok.ID, Running)
+	if op := waitFor(t, q, ok.ID, Succeeded); op.Err != nil || op.Attempts != 1 || op.Finished.IsZero() {
+		t.Errorf("succeeded operation has err %v, %d attempts, finished %v", op.Err, op.Attempts, op.Finished)
+	}
+
+	failing := q.Submit("fail", "b", func(context.Context) error { return errBoom })
+	if op := waitFor(t, q, failing.ID, Failed); !errors.Is(op.Err, errBoom) {
+		t.Errorf("failed operation has err %v, want %v", op.Err, errBoom)
+	}
+	if n := q.Active(); n != 0 {
+		t.Errorf("%d operations active once settled", n)
+	}
+}
+
+func TestQueueRetry(t *testing.T) {
+	q := NewQueue(context.Background())
+
+	var calls atomic.Int32
+	op := q.Submit("flaky", "a", func(context.Context) error {
+		if calls.Add(1) == 1 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	waitFor(t, q, op.ID, Failed)
+
+	if _, err := q.Retry(op.ID); err != nil {
+		t.Fatalf("retrying a failed operation: %v", err)
+	}
+	if done := waitFor(t, q, op.ID, Succeeded); done.Attempts != 2 || done.Err != nil {
+		t.Errorf("retried operation has %d attempts and err %v", done.Attempts, done.Err)
+	}
+
+	if _, err := q.Retry(op.ID); !errors.Is(err, ErrNotFailed) {
+		t.Errorf("retrying a succeeded operation: got %v, want %v", err, ErrNotFailed)
+	}
+	if _, err := q.Retry(op.ID + 100); !errors.Is(err, ErrUnknownOperation) {
+		t.Errorf("retrying an unknown operation: got %v, want %v", err, ErrUnknownOperation)
+	}
+}
+
+func TestQueueHistoryPruning(t *testing.T) {
+	const history = 2
+	q := NewQueue(context.Background(), WithHistory(history))
+
+	var ids []int
+	for range 4 {
+		op := q.Submit("op", "a", func(context.Context) error { return nil })
+		waitFor(t, q, op.ID, Succeeded)
+		ids = append(ids, op.ID)
+	}
+
+	// pruning happens on submit, and never drops operations still pending
+	block := make(chan struct{})
+	pending := q.Submit("pending", "b", func(context.Context) error {
+		<-block
+		return nil
+	})
+	defer close(block)
+
+	recent := q.Recent()
+	if len(recent) != history {
+		t.Fatalf("kept %d operations, want %d", len(recent), history)
+	}
+	if recent[0].ID != pending.ID || recent[1].ID != ids[3] {
+		t.Errorf("kept operations %d and %d, want %d and %d", recent[0].ID, recent[1].ID, pending.ID, ids[3])
+	}
+	if q.Has(ids[0]) {
+		t.Errorf("operation %d still kept after pruning", ids[0])
+	}
+	if _, err := q.Retry(ids[0]); !errors.Is(err, ErrUnknownOperation) {
+		t.Errorf("retrying a pruned operation: got %v, want %v", err, ErrUnknownOperation)
+	}
+}
@@ -0,0 +1,94 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const fileDir = ".config"
+
+// ErrCorrupt is returned along with an empty, usable value when the saved one
+// cannot be read. The unreadable file is kept aside for inspection.
+var ErrCorrupt = errors.New("corrupt")
+
+// File keeps state across runs as JSON in the user config directory.
+type File struct {
+	path string
+	// what the file holds, for errors
+	desc string
+}
+
+// MailboxFile names the file keeping base state for a mailbox, so cleaning up
+// several of them never mixes their state up. The account signed in through
+// OAuth, given as an empty mailbox, keeps the plain base name.
+func MailboxFile(base, mailbox string) string {
+	if mailbox == "" {
+		return base + ".json"
+	}
+	mailbox = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == filepath.Separator {
+			return '_'
+		}
+		return r
+	}, strings.ToLower(mailbox))
+	return base + "-" + mailbox + ".json"
+}
+
+// Open loads the value saved in the file, the zero value if there is none
+// yet, or if it cannot be read, in which case ErrCorrupt is returned as well.
+func Open[T any](name, desc string) (*File, T, error) {
+	var v T
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, v, fmt.Errorf("cannot get user home dir: %w", err)
+	}
+	dir := filepath.Join(home, fileDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, v, fmt.Errorf("cannot create config dir: %w", err)
+	}
+	file := &File{path: filepath.Join(dir, name), desc: desc}
+
+	f, err := os.Open(file.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return file, v, nil
+	}
+	if err != nil {
+		return nil, v, fmt.Errorf("cannot open %s: %w", desc, err)
+	}
+	defer f.Close() //nolint:errcheck
+	if err := json.NewDecoder(f).Decode(&v); err != nil {
+		var zero T
+		aside := file.path + ".corrupt"
+		if rerr := os.Rename(file.path, aside); rerr != nil {
+			return file, zero, fmt.Errorf("%s is %w: %w", desc, ErrCorrupt, err)
+		}
+		return file, zero, fmt.Errorf("%s is %w, moved to %s: %w", desc, ErrCorrupt, aside, err)
+	}
+	return file, v, nil
+}
+
+// Save writes v to a temporary file first and moves it into place, so a
+// crash halfway leaves the previous state whole.
+func (file *File) Save(v any) error {
+	f, err := os.CreateTemp(filepath.Dir(file.path), filepath.Base(file.path)+".*")
+	if err != nil {
+		return fmt.Errorf("cannot save %s: %w", file.desc, err)
+	}
+	defer os.Remove(f.Name()) //nolint:errcheck
+
+	if err := json.NewEncoder(f).Encode(v); err != nil {
+		f.Close() //nolint:errcheck
+		return fmt.Errorf("cannot save %s: %w", file.desc, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("cannot save %s: %w", file.desc, err)
+	}
+	if err := os.Rename(f.Name(), file.path); err != nil {
+		return fmt.Errorf("cannot save %s: %w", file.desc, err)
+	}
+	return nil
+}
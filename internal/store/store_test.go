@@ -0,0 +1,95 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testFile = "geemail-test.json"
+
+type state struct {
+	Names []string `json:"names"`
+}
+
+// tempHome points the user config directory to a directory of its own,
+// returning where the test file is kept.
+func tempHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	return filepath.Join(home, fileDir, testFile)
+}
+
+func TestOpenMissing(t *testing.T) {
+	tempHome(t)
+	file, v, err := Open[state](testFile, "test state")
+	if err != nil || file == nil {
+		t.Fatalf("got %v, %v, want a file to start with", file, err)
+	}
+	if v.Names != nil {
+		t.Errorf("got %+v, want the zero value", v)
+	}
+}
+
+func TestSave(t *testing.T) {
+	path := tempHome(t)
+	file, _, err := Open[state](testFile, "test state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := state{Names: []string{"a", "b"}}
+	if err := file.Save(want); err != nil {
+		t.Fatalf("saving: %v", err)
+	}
+
+	// nothing but the file is left behind in the config dir
+	files, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != testFile {
+		t.Errorf("config dir holds %v, want only %s", files, testFile)
+	}
+
+	_, got, err := Open[state](testFile, "test state")
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("reopened %+v, want %+v", got, want)
+	}
+}
+
+func TestOpenCorrupt(t *testing.T) {
+	path := tempHome(t)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`{"names": ["a", `), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	file, v, err := Open[state](testFile, "test state")
+	if !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("got error %v, want %v", err, ErrCorrupt)
+	}
+	if file == nil {
+		t.Fatal("no file to start over with")
+	}
+	if v.Names != nil {
+		t.Errorf("got %+v from a corrupt file, want the zero value", v)
+	}
+	if _, err := os.Stat(path + ".corrupt"); err != nil {
+		t.Errorf("corrupt file not kept aside: %v", err)
+	}
+
+	if err := file.Save(state{Names: []string{"a"}}); err != nil {
+		t.Fatalf("saving after starting over: %v", err)
+	}
+	if _, _, err := Open[state](testFile, "test state"); err != nil {
+		t.Errorf("reopening after starting over: %v", err)
+	}
+}
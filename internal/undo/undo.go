@@ -0,0 +1,124 @@
+package undo
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sverdejot/geemail/internal/gmail"
+	"github.com/sverdejot/geemail/internal/store"
+)
+
+const (
+	undoFile = "geemail-undo"
+	undoDesc = "undo stack"
+
+	// older actions are forgotten, few would be undone that late
+	maxEntries = 200
+)
+
+// Entry records a reversible action, with the labels it changed on each mail.
+type Entry struct {
+	Action string `json:"action"`
+	// list the mails were grouped into
+	Target  string    `json:"target"`
+	Changes []Change  `json:"changes"`
+	At      time.Time `json:"at"`
+}
+
+// Change is what the action changed on some of its mails.
+type Change struct {
+	IDs     []string `json:"ids"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// NewEntry records action on the mails of a list, as of now.
+func NewEntry(action, target string, changes []gmail.LabelChange) Entry {
+	e := Entry{
+		Action: action,
+		Target: target,
+		At:     time.Now(),
+	}
+	for _, c := range changes {
+		e.Changes = append(e.Changes, Change{IDs: c.IDs, Added: c.Added, Removed: c.Removed})
+	}
+	return e
+}
+
+// IDs lists every mail the action touched.
+func (e Entry) IDs() []string {
+	var ids []string
+	for _, c := range e.Changes {
+		ids = append(ids, c.IDs...)
+	}
+	return ids
+}
+
+// LabelChanges are the label changes the action made, to be reverted.
+func (e Entry) LabelChanges() []gmail.LabelChange {
+	changes := make([]gmail.LabelChange, 0, len(e.Changes))
+	for _, c := range e.Changes {
+		changes = append(changes, gmail.LabelChange{
+			IDs:       c.IDs,
+			LabelDiff: gmail.LabelDiff{Added: c.Added, Removed: c.Removed},
+		})
+	}
+	return changes
+}
+
+// Stack keeps reversible actions across runs, newest last, so they can be
+// undone from a later session as well.
+type Stack struct {
+	mu      sync.Mutex
+	file    *store.File
+	entries []Entry
+}
+
+// Open loads the stack of mailbox from the user config directory, starting an
+// empty one if there is none yet, or if it cannot be read, in which case
+// store.ErrCorrupt is returned as well. An empty mailbox stands for the
+// account signed in through OAuth.
+func Open(mailbox string) (*Stack, error) {
+	file, entries, err := store.Open[[]Entry](store.MailboxFile(undoFile, mailbox), undoDesc)
+	if file == nil {
+		return nil, err
+	}
+	return &Stack{file: file, entries: entries}, err
+}
+
+// Push records e as the latest action and persists the stack.
+func (s *Stack) Push(e Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, e)
+	if len(s.entries) > maxEntries {
+		s.entries = s.entries[len(s.entries)-maxEntries:]
+	}
+	return s.file.Save(s.entries)
+}
+
+// Pop takes the latest action off the stack. Callers push it back should
+// undoing it fail. The action stays on the stack if it cannot be saved
+// without it.
+func (s *Stack) Pop() (Entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.entries) == 0 {
+		return Entry{}, false, nil
+	}
+	e := s.entries[len(s.entries)-1]
+	if err := s.file.Save(s.entries[:len(s.entries)-1]); err != nil {
+		return Entry{}, false, err
+	}
+	s.entries = s.entries[:len(s.entries)-1]
+	return e, true, nil
+}
+
+// Len is how many actions can still be undone.
+func (s *Stack) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
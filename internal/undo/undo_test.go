@@ -0,0 +1,140 @@
+package undo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/sverdejot/geemail/internal/gmail"
+)
+
+// openTemp opens a stack in a home directory of its own.
+func openTemp(t *testing.T) *Stack {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	s, err := Open("")
+	if err != nil {
+		t.Fatalf("opening undo stack: %v", err)
+	}
+	return s
+}
+
+func push(t *testing.T, s *Stack, target string) Entry {
+	t.Helper()
+	e := NewEntry("archive", target, []gmail.LabelChange{
+		{IDs: []string{target + "-1", target + "-2"}, LabelDiff: gmail.ArchiveDiff},
+		{IDs: []string{target + "-3"}},
+	})
+	if err := s.Push(e); err != nil {
+		t.Fatalf("pushing %s: %v", target, err)
+	}
+	return e
+}
+
+func TestPopNewestFirst(t *testing.T) {
+	s := openTemp(t)
+	push(t, s, "a")
+	push(t, s, "b")
+
+	for _, want := range []string{"b", "a"} {
+		e, ok, err := s.Pop()
+		if err != nil || !ok {
+			t.Fatalf("popping: %v, %v", ok, err)
+		}
+		if e.Target != want {
+			t.Errorf("popped %s, want %s", e.Target, want)
+		}
+	}
+	if _, ok, err := s.Pop(); ok || err != nil {
+		t.Errorf("popped from an empty stack: %v, %v", ok, err)
+	}
+}
+
+func TestPopKeepsEntryWhenUnsaved(t *testing.T) {
+	s := openTemp(t)
+	push(t, s, "a")
+
+	// a directory in place of the file makes saving fail
+	paths, _ := filepath.Glob(filepath.Join(os.Getenv("HOME"), "*", undoFile+".json"))
+	if len(paths) != 1 {
+		t.Fatalf("found undo stack files %v, want one", paths)
+	}
+	path := paths[0]
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(path, "blocker"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := s.Pop(); ok || err == nil {
+		t.Fatalf("popping = %v, %v, want an error", ok, err)
+	}
+	if s.Len() != 1 {
+		t.Errorf("Len() = %d after a failed save, want 1", s.Len())
+	}
+}
+
+func TestEntry(t *testing.T) {
+	s := openTemp(t)
+	pushed := push(t, s, "a")
+
+	reopened, err := Open("")
+	if err != nil {
+		t.Fatalf("reopening undo stack: %v", err)
+	}
+	e, ok, err := reopened.Pop()
+	if err != nil || !ok {
+		t.Fatalf("popping: %v, %v", ok, err)
+	}
+	if want := []string{"a-1", "a-2", "a-3"}; !reflect.DeepEqual(e.IDs(), want) {
+		t.Errorf("IDs() = %v, want %v", e.IDs(), want)
+	}
+	if !reflect.DeepEqual(e.LabelChanges(), pushed.LabelChanges()) {
+		t.Errorf("LabelChanges() = %+v, want %+v", e.LabelChanges(), pushed.LabelChanges())
+	}
+}
+
+func TestPushForgetsOldest(t *testing.T) {
+	s := openTemp(t)
+	for i := range maxEntries + 2 {
+		push(t, s, strconv.Itoa(i))
+	}
+	if s.Len() != maxEntries {
+		t.Fatalf("Len() = %d, want %d", s.Len(), maxEntries)
+	}
+
+	reopened, err := Open("")
+	if err != nil {
+		t.Fatalf("reopening undo stack: %v", err)
+	}
+	for range maxEntries - 1 {
+		reopened.Pop() //nolint:errcheck
+	}
+	if e, _, _ := reopened.Pop(); e.Target != "2" {
+		t.Errorf("oldest entry kept is %s, want 2", e.Target)
+	}
+}
+
+func TestOpenPerMailbox(t *testing.T) {
+	alice := openTemp(t)
+	push(t, alice, "a")
+
+	bob, err := Open("bob@example.com")
+	if err != nil {
+		t.Fatalf("opening bob's undo stack: %v", err)
+	}
+	if bob.Len() != 0 {
+		t.Errorf("bob's undo stack holds %d entries of another mailbox", bob.Len())
+	}
+	push(t, bob, "b")
+
+	reopened, err := Open("")
+	if err != nil {
+		t.Fatalf("reopening undo stack: %v", err)
+	}
+	if e, _, _ := reopened.Pop(); e.Target != "a" || reopened.Len() != 0 {
+		t.Errorf("undo stack holds %s and %d more, want only a", e.Target, reopened.Len())
+	}
+}